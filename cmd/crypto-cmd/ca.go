@@ -0,0 +1,188 @@
+package cryptoCmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var caFlags struct {
+	passphrase     string
+	passphraseFile string
+}
+
+var caInitFlags struct {
+	name    string
+	keyType string
+	days    int
+	encrypt bool
+	force   bool
+}
+
+var caIssueFlags struct {
+	dnsNames  []string
+	ips       []string
+	keyType   string
+	days      int
+	client    bool
+	outputDir string
+}
+
+var caRevokeFlags struct {
+	reason string
+}
+
+var CACmd = &cobra.Command{
+	Use:   "ca",
+	Short: "Run a local certificate authority for internal TLS certificates",
+}
+
+var caInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create the root CA under ~/.config/anbu/ca",
+	Run: func(cmd *cobra.Command, args []string) {
+		passphrase := flagPassphrase(caFlags.passphrase, caFlags.passphraseFile)
+		if caInitFlags.encrypt && passphrase == "" {
+			passphrase = promptNewPassword("CA key passphrase")
+		}
+		info, err := anbuCrypto.InitCA(anbuCrypto.CAOptions{
+			Name:       caInitFlags.name,
+			KeyType:    caInitFlags.keyType,
+			Days:       caInitFlags.days,
+			Passphrase: passphrase,
+			Force:      caInitFlags.force,
+		})
+		if err != nil {
+			u.PrintFatal("failed to create CA", err)
+		}
+		u.PrintSuccess("Certificate authority created")
+		u.PrintGeneric(fmt.Sprintf("Subject: %s", u.FInfo(info.Subject)))
+		u.PrintGeneric(fmt.Sprintf("SHA256: %s", u.FInfo(info.Fingerprint)))
+		u.PrintGeneric(fmt.Sprintf("Expires: %s", u.FInfo(info.NotAfter.Format("2006-01-02"))))
+		u.PrintGeneric(fmt.Sprintf("Certificate: %s", u.FInfo(info.CertPath)))
+		u.PrintGeneric(fmt.Sprintf("CRL: %s", u.FInfo(info.CRLPath)))
+		u.PrintInfo("Add the certificate to your system or browser trust store to trust issued certificates")
+	},
+}
+
+var caIssueCmd = &cobra.Command{
+	Use:   "issue <name>",
+	Short: "Issue a leaf certificate with DNS and IP subject alternative names",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		issued, err := withPassphrase("CA key passphrase", flagPassphrase(caFlags.passphrase, caFlags.passphraseFile), func(passphrase string) (*anbuCrypto.CAIssuedCert, error) {
+			return anbuCrypto.IssueCert(anbuCrypto.CertOptions{
+				Name:       args[0],
+				DNSNames:   caIssueFlags.dnsNames,
+				IPs:        caIssueFlags.ips,
+				KeyType:    caIssueFlags.keyType,
+				Days:       caIssueFlags.days,
+				Client:     caIssueFlags.client,
+				OutputDir:  caIssueFlags.outputDir,
+				Passphrase: passphrase,
+			})
+		})
+		if err != nil {
+			u.PrintFatal("failed to issue certificate", err)
+		}
+		u.PrintSuccess(fmt.Sprintf("Issued certificate for %s", issued.Name))
+		u.PrintGeneric(fmt.Sprintf("Serial: %s", u.FInfo(issued.Serial)))
+		u.PrintGeneric(fmt.Sprintf("SANs: %s", u.FInfo(strings.Join(append(issued.DNSNames, issued.IPAddresses...), ", "))))
+		u.PrintGeneric(fmt.Sprintf("Expires: %s", u.FInfo(issued.NotAfter.Format("2006-01-02"))))
+		u.PrintGeneric(fmt.Sprintf("Certificate: %s", u.FInfo(issued.CertPath)))
+		u.PrintGeneric(fmt.Sprintf("Key: %s", u.FInfo(issued.KeyPath)))
+	},
+}
+
+var caRevokeCmd = &cobra.Command{
+	Use:   "revoke <serial|name>",
+	Short: "Revoke an issued certificate and update the CRL",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		reason, ok := anbuCrypto.CRLReasons[caRevokeFlags.reason]
+		if !ok {
+			u.PrintFatal(fmt.Sprintf("unknown reason '%s'", caRevokeFlags.reason), nil)
+		}
+		revoked, err := withPassphrase("CA key passphrase", flagPassphrase(caFlags.passphrase, caFlags.passphraseFile), func(passphrase string) (*anbuCrypto.CAIssuedCert, error) {
+			return anbuCrypto.RevokeCert(args[0], reason, passphrase)
+		})
+		if err != nil {
+			u.PrintFatal("failed to revoke certificate", err)
+		}
+		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(revoked.Name), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess("revoked, serial "+revoked.Serial)))
+	},
+}
+
+var caCRLCmd = &cobra.Command{
+	Use:   "crl",
+	Short: "Re-sign the CRL before its next update time passes",
+	Run: func(cmd *cobra.Command, args []string) {
+		crlPath, err := withPassphrase("CA key passphrase", flagPassphrase(caFlags.passphrase, caFlags.passphraseFile), anbuCrypto.RefreshCRL)
+		if err != nil {
+			u.PrintFatal("failed to refresh CRL", err)
+		}
+		u.PrintSuccess(fmt.Sprintf("CRL written to %s", crlPath))
+	},
+}
+
+var caListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List certificates issued by the local CA",
+	Run: func(cmd *cobra.Command, args []string) {
+		certs, err := anbuCrypto.ListIssuedCerts()
+		if err != nil {
+			u.PrintFatal("failed to list certificates", err)
+		}
+		if len(certs) == 0 {
+			u.PrintInfo("No certificates issued")
+			return
+		}
+		sort.Slice(certs, func(i, j int) bool { return certs[i].IssuedAt.Before(certs[j].IssuedAt) })
+		table := u.NewTable([]string{"Name", "Serial", "SANs", "Expires", "Status"})
+		for _, cert := range certs {
+			status := "valid"
+			if cert.RevokedAt != nil {
+				status = "revoked"
+			}
+			table.Rows = append(table.Rows, []string{
+				cert.Name,
+				cert.Serial,
+				strings.Join(append(cert.DNSNames, cert.IPAddresses...), ", "),
+				cert.NotAfter.Format("2006-01-02"),
+				status,
+			})
+		}
+		table.PrintTable(false)
+	},
+}
+
+func init() {
+	CACmd.AddCommand(caInitCmd)
+	CACmd.AddCommand(caIssueCmd)
+	CACmd.AddCommand(caRevokeCmd)
+	CACmd.AddCommand(caCRLCmd)
+	CACmd.AddCommand(caListCmd)
+
+	CACmd.PersistentFlags().StringVarP(&caFlags.passphrase, "passphrase", "p", "", passphraseUsage("Passphrase of the CA key"))
+	CACmd.PersistentFlags().StringVar(&caFlags.passphraseFile, "passphrase-file", "", "Read the CA key passphrase from a file (prompted for if the key is encrypted)")
+	CACmd.MarkFlagsMutuallyExclusive("passphrase", "passphrase-file")
+
+	caInitCmd.Flags().StringVarP(&caInitFlags.name, "name", "n", anbuCrypto.DefaultCAName, "Common name of the CA")
+	caInitCmd.Flags().StringVarP(&caInitFlags.keyType, "type", "t", anbuCrypto.KeyTypeECDSAP256, fmt.Sprintf("Key type (%s)", strings.Join(anbuCrypto.SupportedKeyTypes, ", ")))
+	caInitCmd.Flags().IntVarP(&caInitFlags.days, "days", "d", anbuCrypto.DefaultCADays, "Validity in days")
+	caInitCmd.Flags().BoolVarP(&caInitFlags.encrypt, "encrypt", "e", false, "Prompt for a passphrase to encrypt the CA key")
+	caInitCmd.Flags().BoolVar(&caInitFlags.force, "force", false, "Replace an existing CA")
+
+	caIssueCmd.Flags().StringSliceVar(&caIssueFlags.dnsNames, "dns", nil, "DNS subject alternative name (repeatable, defaults to the name)")
+	caIssueCmd.Flags().StringSliceVar(&caIssueFlags.ips, "ip", nil, "IP subject alternative name (repeatable)")
+	caIssueCmd.Flags().StringVarP(&caIssueFlags.keyType, "type", "t", anbuCrypto.KeyTypeECDSAP256, fmt.Sprintf("Key type (%s)", strings.Join(anbuCrypto.SupportedKeyTypes, ", ")))
+	caIssueCmd.Flags().IntVarP(&caIssueFlags.days, "days", "d", anbuCrypto.DefaultCertDays, "Validity in days")
+	caIssueCmd.Flags().BoolVar(&caIssueFlags.client, "client", false, "Also allow the certificate for TLS client authentication")
+	caIssueCmd.Flags().StringVarP(&caIssueFlags.outputDir, "output", "o", "", "Output directory (defaults to ~/.config/anbu/ca/issued)")
+
+	caRevokeCmd.Flags().StringVarP(&caRevokeFlags.reason, "reason", "r", "unspecified", "Revocation reason (e.g., key-compromise, superseded)")
+}
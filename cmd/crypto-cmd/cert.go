@@ -0,0 +1,129 @@
+package cryptoCmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var certCSRFlags struct {
+	commonName     string
+	organization   string
+	dnsNames       []string
+	ips            []string
+	emails         []string
+	keyPath        string
+	passphrase     string
+	passphraseFile string
+	keyType        string
+	outputPath     string
+	force          bool
+}
+
+var CertCmd = &cobra.Command{
+	Use:   "cert",
+	Short: "Create certificate signing requests and inspect X.509 certificates",
+}
+
+var certCSRCmd = &cobra.Command{
+	Use:   "csr",
+	Short: "Create a PKCS#10 certificate signing request",
+	Run: func(cmd *cobra.Command, args []string) {
+		result, err := withPassphrase("Key passphrase", flagPassphrase(certCSRFlags.passphrase, certCSRFlags.passphraseFile), func(passphrase string) (*anbuCrypto.CSRResult, error) {
+			return anbuCrypto.CreateCSR(anbuCrypto.CSROptions{
+				CommonName:    certCSRFlags.commonName,
+				Organization:  certCSRFlags.organization,
+				DNSNames:      certCSRFlags.dnsNames,
+				IPs:           certCSRFlags.ips,
+				Emails:        certCSRFlags.emails,
+				KeyPath:       certCSRFlags.keyPath,
+				KeyPassphrase: passphrase,
+				KeyType:       certCSRFlags.keyType,
+				OutputPath:    certCSRFlags.outputPath,
+				Force:         certCSRFlags.force,
+			})
+		})
+		if err != nil {
+			u.PrintFatal("failed to create CSR", err)
+		}
+		u.PrintSuccess(fmt.Sprintf("CSR written to %s", result.CSRPath))
+		u.PrintGeneric(fmt.Sprintf("Key type: %s", u.FInfo(result.KeyType)))
+		u.PrintGeneric(fmt.Sprintf("SHA256: %s", u.FInfo(result.Fingerprint)))
+		if result.KeyPath != "" {
+			u.PrintGeneric(fmt.Sprintf("New private key: %s", u.FInfo(result.KeyPath)))
+		}
+	},
+}
+
+var certShowCmd = &cobra.Command{
+	Use:   "show <file|->",
+	Short: "Show certificates, chains and CSRs in PEM or DER form",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var data []byte
+		var err error
+		if args[0] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			u.PrintFatal("failed to read input", err)
+		}
+		certs, csrs, err := anbuCrypto.ParseCertificateData(data)
+		if err != nil {
+			u.PrintFatal("failed to parse input", err)
+		}
+		for i, cert := range certs {
+			if len(certs) > 1 {
+				u.PrintInfo(fmt.Sprintf("Certificate #%d", i+1))
+			}
+			table := u.NewTable([]string{"Property", "Value"})
+			table.Rows = anbuCrypto.CertificateFields(cert)
+			table.PrintTable(false)
+		}
+		if len(certs) > 1 {
+			u.PrintInfo("Chain order")
+			table := u.NewTable([]string{"#", "Subject", "Issuer", "Link"})
+			for i, status := range anbuCrypto.CheckChainOrder(certs) {
+				table.Rows = append(table.Rows, []string{
+					fmt.Sprintf("%d", i+1),
+					certs[i].Subject.CommonName,
+					certs[i].Issuer.CommonName,
+					status,
+				})
+			}
+			table.PrintTable(false)
+		}
+		for _, csr := range csrs {
+			u.PrintInfo("Certificate request")
+			table := u.NewTable([]string{"Property", "Value"})
+			table.Rows = anbuCrypto.CSRFields(csr)
+			table.PrintTable(false)
+		}
+	},
+}
+
+func init() {
+	CertCmd.AddCommand(certCSRCmd)
+	CertCmd.AddCommand(certShowCmd)
+
+	certCSRCmd.Flags().StringVar(&certCSRFlags.commonName, "cn", "", "Subject common name (defaults to the first DNS name)")
+	certCSRCmd.Flags().StringVarP(&certCSRFlags.organization, "org", "O", "", "Subject organization")
+	certCSRCmd.Flags().StringSliceVar(&certCSRFlags.dnsNames, "dns", nil, "DNS subject alternative name (repeatable)")
+	certCSRCmd.Flags().StringSliceVar(&certCSRFlags.ips, "ip", nil, "IP subject alternative name (repeatable)")
+	certCSRCmd.Flags().StringSliceVar(&certCSRFlags.emails, "email", nil, "Email subject alternative name (repeatable)")
+	certCSRCmd.Flags().StringVarP(&certCSRFlags.keyPath, "key", "k", "", "Existing private key to sign the request with")
+	certCSRCmd.Flags().StringVarP(&certCSRFlags.passphrase, "passphrase", "p", "", passphraseUsage("Passphrase of an encrypted key"))
+	certCSRCmd.Flags().StringVar(&certCSRFlags.passphraseFile, "passphrase-file", "", "Read the key passphrase from a file (prompted for if needed)")
+	certCSRCmd.Flags().StringVarP(&certCSRFlags.keyType, "type", "t", anbuCrypto.KeyTypeECDSAP256, fmt.Sprintf("Type of the new key when --key is not given (%s)", strings.Join(anbuCrypto.SupportedKeyTypes, ", ")))
+	certCSRCmd.Flags().StringVarP(&certCSRFlags.outputPath, "output", "o", "", "Output path of the CSR (defaults to <cn>.csr)")
+	certCSRCmd.Flags().BoolVar(&certCSRFlags.force, "force", false, "Replace an existing <cn>.key when generating a new key")
+	certCSRCmd.MarkFlagsMutuallyExclusive("key", "type")
+	certCSRCmd.MarkFlagsMutuallyExclusive("passphrase", "passphrase-file")
+}
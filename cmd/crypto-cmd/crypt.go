@@ -0,0 +1,144 @@
+package cryptoCmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var cryptFlags struct {
+	recipients     []string
+	recipientFiles []string
+	identities     []string
+	passphrase     string
+	passphraseFile string
+	armor          bool
+	outputPath     string
+}
+
+var cryptKeygenFlags struct {
+	outputPath string
+}
+
+var CryptCmd = &cobra.Command{
+	Use:   "crypt",
+	Short: "Encrypt and decrypt files and streams with passphrases or public keys (age format)",
+}
+
+func stdinPiped(inputPath string) bool {
+	if inputPath != "-" {
+		return false
+	}
+	stat, err := os.Stdin.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice == 0
+}
+
+// cryptPrompt refuses to prompt when the data itself arrives on stdin
+func cryptPrompt(inputPath string) func(string) (string, error) {
+	return func(label string) (string, error) {
+		if stdinPiped(inputPath) {
+			return "", fmt.Errorf("input is read from stdin, pass the passphrase with --passphrase-file")
+		}
+		return u.PromptPassword(label + ":")
+	}
+}
+
+func cryptInput(args []string) string {
+	if len(args) == 0 {
+		return "-"
+	}
+	return args[0]
+}
+
+func cryptOutput(inputPath string, decrypt bool) string {
+	if cryptFlags.outputPath != "" {
+		return cryptFlags.outputPath
+	}
+	return anbuCrypto.CryptOutputPath(inputPath, decrypt)
+}
+
+var cryptEncryptCmd = &cobra.Command{
+	Use:   "encrypt [file]",
+	Short: "Encrypt a file or stdin to recipients or with a passphrase",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		inputPath := cryptInput(args)
+		outputPath := cryptOutput(inputPath, false)
+		opts := anbuCrypto.CryptOptions{
+			Recipients:     cryptFlags.recipients,
+			RecipientFiles: cryptFlags.recipientFiles,
+			Passphrase:     flagPassphrase(cryptFlags.passphrase, cryptFlags.passphraseFile),
+			Armor:          cryptFlags.armor,
+		}
+		if len(opts.Recipients) == 0 && len(opts.RecipientFiles) == 0 && opts.Passphrase == "" {
+			if stdinPiped(inputPath) {
+				u.PrintFatal("no recipients given, pass --recipient or --passphrase-file when reading from stdin", nil)
+			}
+			opts.Passphrase = promptNewPassword("Passphrase")
+		}
+		if err := anbuCrypto.EncryptFile(inputPath, outputPath, opts); err != nil {
+			u.PrintFatal("encryption failed", err)
+		}
+		if outputPath != "-" {
+			u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(inputPath), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess(outputPath)))
+		}
+	},
+}
+
+var cryptDecryptCmd = &cobra.Command{
+	Use:   "decrypt [file]",
+	Short: "Decrypt an age file or stdin with identities or a passphrase",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		inputPath := cryptInput(args)
+		outputPath := cryptOutput(inputPath, true)
+		opts := anbuCrypto.CryptOptions{
+			Identities:       cryptFlags.identities,
+			Passphrase:       flagPassphrase(cryptFlags.passphrase, cryptFlags.passphraseFile),
+			PromptPassphrase: cryptPrompt(inputPath),
+		}
+		if err := anbuCrypto.DecryptFile(inputPath, outputPath, opts); err != nil {
+			u.PrintFatal("decryption failed", err)
+		}
+		if outputPath != "-" {
+			u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(inputPath), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess(outputPath)))
+		}
+	},
+}
+
+var cryptKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate an X25519 identity (compatible with age-keygen)",
+	Run: func(cmd *cobra.Command, args []string) {
+		recipient, err := anbuCrypto.GenerateAgeIdentity(cryptKeygenFlags.outputPath)
+		if err != nil {
+			u.PrintFatal("failed to generate identity", err)
+		}
+		if cryptKeygenFlags.outputPath != "" {
+			u.PrintSuccess(fmt.Sprintf("Identity written to %s", cryptKeygenFlags.outputPath))
+			u.PrintGeneric(fmt.Sprintf("Public key: %s", u.FInfo(recipient)))
+		}
+	},
+}
+
+func init() {
+	CryptCmd.AddCommand(cryptEncryptCmd)
+	CryptCmd.AddCommand(cryptDecryptCmd)
+	CryptCmd.AddCommand(cryptKeygenCmd)
+
+	for _, cmd := range []*cobra.Command{cryptEncryptCmd, cryptDecryptCmd} {
+		cmd.Flags().StringVarP(&cryptFlags.passphrase, "passphrase", "p", "", passphraseUsage("Passphrase"))
+		cmd.Flags().StringVar(&cryptFlags.passphraseFile, "passphrase-file", "", "Read the passphrase from a file (prompted for when needed)")
+		cmd.MarkFlagsMutuallyExclusive("passphrase", "passphrase-file")
+		cmd.Flags().StringVarP(&cryptFlags.outputPath, "output", "o", "", "Output file, - for stdout (defaults to adding or removing .age)")
+	}
+	cryptEncryptCmd.Flags().StringArrayVarP(&cryptFlags.recipients, "recipient", "r", nil, "Recipient: age1..., ssh-ed25519 ... or ssh-rsa ... (repeatable)")
+	cryptEncryptCmd.Flags().StringArrayVarP(&cryptFlags.recipientFiles, "recipients-file", "R", nil, "File with recipients, one per line, or a public key from 'anbu kp' (repeatable)")
+	cryptEncryptCmd.Flags().BoolVarP(&cryptFlags.armor, "armor", "a", false, "Write PEM-armored output")
+	cryptDecryptCmd.Flags().StringArrayVarP(&cryptFlags.identities, "identity", "i", nil, "age identity file or Ed25519/RSA private key (repeatable)")
+
+	cryptKeygenCmd.Flags().StringVarP(&cryptKeygenFlags.outputPath, "output", "o", "", "Write the identity to a file instead of stdout")
+}
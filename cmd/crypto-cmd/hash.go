@@ -0,0 +1,108 @@
+package cryptoCmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var hashFlags struct {
+	algorithm    string
+	workers      int
+	manifestPath string
+}
+
+var HashCmd = &cobra.Command{
+	Use:   "hash [paths...]",
+	Short: "Hash files, directories or stdin with MD5, SHA1, SHA256, SHA512, BLAKE2b or BLAKE3",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 || (len(args) == 1 && args[0] == "-") {
+			sum, err := anbuCrypto.HashReader(os.Stdin, hashFlags.algorithm)
+			if err != nil {
+				u.PrintFatal("failed to hash stdin", err)
+			}
+			u.PrintGeneric(sum)
+			return
+		}
+		hashes, err := anbuCrypto.HashFiles(args, hashFlags.algorithm, hashFlags.workers)
+		if err != nil {
+			u.PrintFatal("failed to hash files", err)
+		}
+		for _, fileHash := range hashes {
+			if fileHash.Err != nil {
+				u.PrintWarn(fmt.Sprintf("failed to hash %s", fileHash.Path), fileHash.Err)
+			}
+		}
+		if hashFlags.manifestPath != "" {
+			if err := anbuCrypto.WriteHashManifest(hashFlags.manifestPath, hashFlags.algorithm, hashes); err != nil {
+				u.PrintFatal("failed to write manifest", err)
+			}
+			if hashFlags.manifestPath != "-" {
+				u.PrintSuccess(fmt.Sprintf("%s manifest with %d files written to %s", hashFlags.algorithm, len(hashes), hashFlags.manifestPath))
+			}
+			return
+		}
+		table := u.NewTable([]string{strings.ToUpper(hashFlags.algorithm), "File"})
+		for _, fileHash := range hashes {
+			if fileHash.Err == nil {
+				table.Rows = append(table.Rows, []string{fileHash.Hash, fileHash.Path})
+			}
+		}
+		table.PrintTable(false)
+	},
+}
+
+var hashVerifyCmd = &cobra.Command{
+	Use:   "verify <manifest>",
+	Short: "Check files against a SHA256SUMS-style manifest and report mismatched, missing and extra files",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		algorithm := ""
+		if cmd.Flags().Changed("algorithm") {
+			algorithm = hashFlags.algorithm
+		}
+		results, algorithm, err := anbuCrypto.VerifyHashManifest(args[0], algorithm, hashFlags.workers)
+		if err != nil {
+			u.PrintFatal("failed to verify manifest", err)
+		}
+		counts := map[string]int{}
+		table := u.NewTable([]string{"Status", "File", "Details"})
+		for _, result := range results {
+			counts[result.Status]++
+			switch result.Status {
+			case anbuCrypto.HashStatusOK:
+				continue
+			case anbuCrypto.HashStatusMismatch:
+				table.Rows = append(table.Rows, []string{u.FError(result.Status), result.Path, "got " + result.Actual})
+			case anbuCrypto.HashStatusExtra:
+				table.Rows = append(table.Rows, []string{u.FWarning(result.Status), result.Path, "not in manifest"})
+			case anbuCrypto.HashStatusError:
+				table.Rows = append(table.Rows, []string{u.FError(result.Status), result.Path, result.Actual})
+			default:
+				table.Rows = append(table.Rows, []string{u.FError(result.Status), result.Path, "listed but not found"})
+			}
+		}
+		if len(table.Rows) > 0 {
+			table.PrintTable(false)
+		}
+		u.PrintInfo(fmt.Sprintf("%s: %d ok, %d mismatched, %d missing, %d extra", algorithm,
+			counts[anbuCrypto.HashStatusOK], counts[anbuCrypto.HashStatusMismatch], counts[anbuCrypto.HashStatusMissing], counts[anbuCrypto.HashStatusExtra]))
+		if failed := counts[anbuCrypto.HashStatusMismatch] + counts[anbuCrypto.HashStatusMissing] + counts[anbuCrypto.HashStatusError]; failed > 0 {
+			u.PrintFatal(fmt.Sprintf("%d files failed verification", failed), nil)
+		}
+		u.PrintSuccess("All listed files match")
+	},
+}
+
+func init() {
+	HashCmd.AddCommand(hashVerifyCmd)
+
+	HashCmd.PersistentFlags().StringVarP(&hashFlags.algorithm, "algorithm", "a", anbuCrypto.HashSHA256, fmt.Sprintf("Hash algorithm (%s); verify detects it from the manifest by default", strings.Join(anbuCrypto.HashAlgorithms, ", ")))
+	HashCmd.PersistentFlags().IntVarP(&hashFlags.workers, "workers", "w", runtime.NumCPU(), "Number of files hashed in parallel")
+	HashCmd.Flags().StringVarP(&hashFlags.manifestPath, "manifest", "m", "", "Write a SHA256SUMS-style manifest to this file (- for stdout)")
+}
@@ -0,0 +1,206 @@
+package cryptoCmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	anbuGenerics "github.com/tanq16/anbu/internal/generics"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var jwtVerifyFlags struct {
+	keyPath        string
+	jwksPath       string
+	secret         string
+	passphrase     string
+	passphraseFile string
+	leeway         time.Duration
+}
+
+var jwtSignFlags struct {
+	claimsPath     string
+	keyPath        string
+	secret         string
+	passphrase     string
+	passphraseFile string
+	algorithm      string
+	keyID          string
+	expiresIn      time.Duration
+}
+
+var JWTCmd = &cobra.Command{
+	Use:   "jwt",
+	Short: "Decode, verify and sign JSON Web Tokens",
+}
+
+// jwtToken takes the token from the argument or, when omitted, from stdin
+func jwtToken(args []string) string {
+	token := ""
+	if len(args) > 0 {
+		token = args[0]
+	} else {
+		token = u.ReadPipedInput()
+	}
+	if token == "" {
+		u.PrintFatal("no token given as argument or on stdin", nil)
+	}
+	return token
+}
+
+func printJWTJSON(title string, raw []byte) {
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, raw, "", "  "); err != nil {
+		pretty.Write(raw)
+	}
+	u.PrintInfo(title)
+	u.PrintGeneric(pretty.String())
+}
+
+func printJWT(jwt *anbuCrypto.JWT) {
+	printJWTJSON("Header", jwt.RawHeader)
+	printJWTJSON("Claims", jwt.RawClaims)
+	var present []string
+	var times []time.Time
+	for _, name := range anbuCrypto.JWTTimeClaims {
+		if t, ok := jwt.TimeClaim(name); ok {
+			present = append(present, name)
+			times = append(times, t)
+		}
+	}
+	if len(present) == 0 {
+		return
+	}
+	u.PrintInfo("Time claims")
+	table := u.NewTable(append([]string{"Format"}, present...))
+	relative := []string{"Relative"}
+	for _, t := range times {
+		relative = append(relative, anbuGenerics.TimeRelative(t))
+	}
+	table.Rows = append(table.Rows, relative)
+	formats := make([][][]string, len(times))
+	for i, t := range times {
+		formats[i] = anbuGenerics.TimeFormatRows(t)
+	}
+	for row := range formats[0] {
+		values := []string{formats[0][row][0]}
+		for i := range times {
+			values = append(values, formats[i][row][1])
+		}
+		table.Rows = append(table.Rows, values)
+	}
+	table.PrintTable(false)
+}
+
+var jwtDecodeCmd = &cobra.Command{
+	Use:   "decode [token]",
+	Short: "Pretty-print the header and claims of a JWT without verifying it",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		jwt, err := anbuCrypto.ParseJWT(jwtToken(args))
+		if err != nil {
+			u.PrintFatal("failed to decode token", err)
+		}
+		printJWT(jwt)
+		if exp, ok := jwt.TimeClaim("exp"); ok && time.Now().After(exp) {
+			u.PrintWarn(fmt.Sprintf("Token expired %s", anbuGenerics.TimeRelative(exp)), nil)
+		}
+	},
+}
+
+var jwtVerifyCmd = &cobra.Command{
+	Use:   "verify [token]",
+	Short: "Verify HS, RS, PS, ES and EdDSA signatures offline against a key, JWKS or secret",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		token := jwtToken(args)
+		type verified struct {
+			jwt *anbuCrypto.JWT
+			key string
+		}
+		result, err := withPassphrase("Key passphrase", flagPassphrase(jwtVerifyFlags.passphrase, jwtVerifyFlags.passphraseFile), func(passphrase string) (verified, error) {
+			jwt, key, err := anbuCrypto.VerifyJWT(token, anbuCrypto.JWTVerifyOptions{
+				KeyPath:    jwtVerifyFlags.keyPath,
+				Passphrase: passphrase,
+				JWKSPath:   jwtVerifyFlags.jwksPath,
+				Secret:     jwtVerifyFlags.secret,
+				Leeway:     jwtVerifyFlags.leeway,
+			})
+			return verified{jwt, key}, err
+		})
+		if err != nil {
+			if result.key != "" {
+				u.PrintWarn(fmt.Sprintf("Signature is valid (%s)", result.key), nil)
+				u.PrintFatal(err.Error(), nil)
+			}
+			u.PrintFatal("token verification failed", err)
+		}
+		printJWT(result.jwt)
+		u.PrintSuccess(fmt.Sprintf("Valid %s signature from %s", result.jwt.Algorithm(), result.key))
+	},
+}
+
+var jwtSignCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "Mint a JWT from a JSON claims file for testing local services",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var claims []byte
+		var err error
+		switch jwtSignFlags.claimsPath {
+		case "":
+		case "-":
+			claims, err = io.ReadAll(os.Stdin)
+		default:
+			claims, err = os.ReadFile(jwtSignFlags.claimsPath)
+		}
+		if err != nil {
+			u.PrintFatal("failed to read claims", err)
+		}
+		token, err := withPassphrase("Key passphrase", flagPassphrase(jwtSignFlags.passphrase, jwtSignFlags.passphraseFile), func(passphrase string) (string, error) {
+			return anbuCrypto.SignJWT(anbuCrypto.JWTSignOptions{
+				Claims:     claims,
+				KeyPath:    jwtSignFlags.keyPath,
+				Passphrase: passphrase,
+				Secret:     jwtSignFlags.secret,
+				Algorithm:  jwtSignFlags.algorithm,
+				KeyID:      jwtSignFlags.keyID,
+				ExpiresIn:  jwtSignFlags.expiresIn,
+			})
+		})
+		if err != nil {
+			u.PrintFatal("failed to sign token", err)
+		}
+		u.PrintGeneric(token)
+	},
+}
+
+func init() {
+	JWTCmd.AddCommand(jwtDecodeCmd)
+	JWTCmd.AddCommand(jwtVerifyCmd)
+	JWTCmd.AddCommand(jwtSignCmd)
+
+	jwtVerifyCmd.Flags().StringVarP(&jwtVerifyFlags.keyPath, "key", "k", "", "Public key (PEM, OpenSSH or JWK); a private key also works")
+	jwtVerifyCmd.Flags().StringVar(&jwtVerifyFlags.jwksPath, "jwks", "", "JWKS file; the key is picked by the token's kid")
+	jwtVerifyCmd.Flags().StringVarP(&jwtVerifyFlags.secret, "secret", "s", "", "Shared secret for HS256, HS384 and HS512")
+	jwtVerifyCmd.Flags().StringVarP(&jwtVerifyFlags.passphrase, "passphrase", "p", "", passphraseUsage("Passphrase when --key is an encrypted private key"))
+	jwtVerifyCmd.Flags().StringVar(&jwtVerifyFlags.passphraseFile, "passphrase-file", "", "Read the passphrase of an encrypted --key from a file")
+	jwtVerifyCmd.Flags().DurationVar(&jwtVerifyFlags.leeway, "leeway", 0, "Clock skew allowed for exp and nbf (e.g., 30s)")
+	jwtVerifyCmd.MarkFlagsMutuallyExclusive("passphrase", "passphrase-file")
+
+	jwtSignCmd.Flags().StringVarP(&jwtSignFlags.claimsPath, "claims", "c", "", "JSON claims file, - for stdin (iat is added when missing)")
+	jwtSignCmd.Flags().StringVarP(&jwtSignFlags.keyPath, "key", "k", "", "Private key (PEM, OpenSSH or JWK)")
+	jwtSignCmd.Flags().StringVarP(&jwtSignFlags.secret, "secret", "s", "", "Shared secret for HS algorithms")
+	jwtSignCmd.Flags().StringVarP(&jwtSignFlags.passphrase, "passphrase", "p", "", passphraseUsage("Passphrase of an encrypted key"))
+	jwtSignCmd.Flags().StringVar(&jwtSignFlags.passphraseFile, "passphrase-file", "", "Read the key passphrase from a file (prompted for if needed)")
+	jwtSignCmd.Flags().StringVarP(&jwtSignFlags.algorithm, "alg", "a", "", "Algorithm, e.g., HS256, RS256, PS256, ES256 or EdDSA (defaults to the key type)")
+	jwtSignCmd.Flags().StringVar(&jwtSignFlags.keyID, "kid", "", "Key ID to put in the header")
+	jwtSignCmd.Flags().DurationVarP(&jwtSignFlags.expiresIn, "expires-in", "e", 0, "Set exp this far in the future (e.g., 1h)")
+	jwtSignCmd.MarkFlagsMutuallyExclusive("key", "secret")
+	jwtSignCmd.MarkFlagsMutuallyExclusive("passphrase", "passphrase-file")
+}
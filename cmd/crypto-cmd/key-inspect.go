@@ -0,0 +1,110 @@
+package cryptoCmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var keyInspectFlags struct {
+	passphrase     string
+	passphraseFile string
+}
+
+var keyConvertFlags struct {
+	to             string
+	outputPath     string
+	passphrase     string
+	passphraseFile string
+	public         bool
+}
+
+// withPassphrase retries once with a prompted passphrase when the key is encrypted
+func withPassphrase[T any](label, passphrase string, fn func(string) (T, error)) (T, error) {
+	result, err := fn(passphrase)
+	if errors.Is(err, anbuCrypto.ErrPassphraseRequired) {
+		if passphrase, err = u.PromptPassword(label + ":"); err != nil {
+			return result, err
+		}
+		return fn(passphrase)
+	}
+	return result, err
+}
+
+var keyInspectCmd = &cobra.Command{
+	Use:   "inspect <file>",
+	Short: "Show the type, size, fingerprints and comment of a public or private key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		result, err := withPassphrase("Key passphrase", flagPassphrase(keyInspectFlags.passphrase, keyInspectFlags.passphraseFile), func(passphrase string) (*anbuCrypto.KeyPairResult, error) {
+			return anbuCrypto.InspectKey(args[0], passphrase)
+		})
+		if err != nil {
+			u.PrintFatal("failed to inspect key", err)
+		}
+		kind := "public"
+		if result.Private {
+			kind = "private"
+		}
+		comment := result.Comment
+		if comment == "" {
+			comment = "-"
+		}
+		table := u.NewTable([]string{"Property", "Value"})
+		table.Rows = append(table.Rows,
+			[]string{"Key", kind},
+			[]string{"Format", result.Format},
+			[]string{"Algorithm", result.Type},
+			[]string{"Size", fmt.Sprintf("%d bits", result.KeySize)},
+			[]string{"Encrypted", fmt.Sprintf("%t", result.Encrypted)},
+			[]string{"SHA256", result.Fingerprint},
+			[]string{"MD5", result.FingerprintMD5},
+			[]string{"Comment", comment},
+		)
+		table.PrintTable(false)
+	},
+}
+
+var keyConvertCmd = &cobra.Command{
+	Use:   "convert <file>",
+	Short: "Convert a key between OpenSSH, PKCS#8, PKCS#1 and JWK",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		result, err := withPassphrase("Key passphrase", flagPassphrase(keyConvertFlags.passphrase, keyConvertFlags.passphraseFile), func(passphrase string) (*anbuCrypto.KeyPairResult, error) {
+			return anbuCrypto.ConvertKey(args[0], keyConvertFlags.outputPath, keyConvertFlags.to, passphrase, keyConvertFlags.public)
+		})
+		if err != nil {
+			u.PrintFatal("failed to convert key", err)
+		}
+		if keyConvertFlags.outputPath == "" {
+			return
+		}
+		kind := "Public"
+		if result.Private {
+			kind = "Private"
+		}
+		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(args[0]), u.FInfo(u.StyleSymbols["arrow"]),
+			u.FSuccess(fmt.Sprintf("%s key written to %s (%s)", kind, keyConvertFlags.outputPath, result.Format))))
+	},
+}
+
+func init() {
+	KeyPairCmd.AddCommand(keyInspectCmd)
+	KeyPairCmd.AddCommand(keyConvertCmd)
+
+	keyInspectCmd.Flags().StringVarP(&keyInspectFlags.passphrase, "passphrase", "p", "", passphraseUsage("Passphrase for encrypted PKCS#8 keys"))
+	keyInspectCmd.Flags().StringVar(&keyInspectFlags.passphraseFile, "passphrase-file", "", "Read the key passphrase from a file (prompted for if needed)")
+	keyInspectCmd.MarkFlagsMutuallyExclusive("passphrase", "passphrase-file")
+
+	keyConvertCmd.Flags().StringVar(&keyConvertFlags.to, "to", "", fmt.Sprintf("Output format (%s)", strings.Join(anbuCrypto.KeyConvertFormats, ", ")))
+	keyConvertCmd.Flags().StringVarP(&keyConvertFlags.outputPath, "output", "o", "", "Output file (defaults to stdout)")
+	keyConvertCmd.Flags().StringVarP(&keyConvertFlags.passphrase, "passphrase", "p", "", passphraseUsage("Passphrase of an encrypted key"))
+	keyConvertCmd.Flags().StringVar(&keyConvertFlags.passphraseFile, "passphrase-file", "", "Read the key passphrase from a file (prompted for if needed)")
+	keyConvertCmd.MarkFlagsMutuallyExclusive("passphrase", "passphrase-file")
+	keyConvertCmd.Flags().BoolVar(&keyConvertFlags.public, "public", false, "Only write the public key")
+	keyConvertCmd.MarkFlagRequired("to")
+}
@@ -2,7 +2,9 @@ package cryptoCmd
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
@@ -10,41 +12,142 @@ import (
 )
 
 var keyPairFlags struct {
-	outputPath string
-	keySize    int
-	sshFormat  bool
+	outputPath     string
+	keyType        string
+	keySize        int
+	sshFormat      bool
+	comment        string
+	pkcs8          bool
+	passphrase     string
+	passphraseFile string
+	encrypt        bool
+}
+
+var changePassphraseFlags struct {
+	oldPassphrase     string
+	oldPassphraseFile string
+	newPassphrase     string
+	newPassphraseFile string
+	remove            bool
+}
+
+// passphraseUsage flags plain passphrase values, which end up in shell history
+func passphraseUsage(usage string) string {
+	return usage + " (visible in shell history, prefer the prompt or a passphrase file)"
+}
+
+// flagPassphrase returns the passphrase from a file flag when one is given,
+// otherwise the plain flag value; only a trailing line break is removed
+func flagPassphrase(passphrase, passphraseFile string) string {
+	if passphraseFile == "" {
+		return passphrase
+	}
+	data, err := os.ReadFile(passphraseFile)
+	if err != nil {
+		u.PrintFatal("failed to read passphrase file", err)
+	}
+	passphrase = strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+	if passphrase == "" {
+		u.PrintFatal(fmt.Sprintf("passphrase file %s is empty", passphraseFile), nil)
+	}
+	return passphrase
 }
 
 var KeyPairCmd = &cobra.Command{
 	Use:     "key-pair",
 	Aliases: []string{"kp"},
-	Short:   "Generate RSA key pairs in PEM or SSH format",
+	Short:   "Generate Ed25519, ECDSA or RSA key pairs in PEM or OpenSSH format",
 	Run: func(cmd *cobra.Command, args []string) {
 		keyName := filepath.Base(keyPairFlags.outputPath)
 		keyDir := filepath.Dir(keyPairFlags.outputPath)
+		opts := anbuCrypto.KeyPairOptions{
+			Type:       keyPairFlags.keyType,
+			KeySize:    keyPairFlags.keySize,
+			Comment:    keyPairFlags.comment,
+			PKCS8:      keyPairFlags.pkcs8,
+			Passphrase: flagPassphrase(keyPairFlags.passphrase, keyPairFlags.passphraseFile),
+		}
+		if keyPairFlags.encrypt {
+			opts.Passphrase = promptNewPassword("Key passphrase")
+		}
 		var result *anbuCrypto.KeyPairResult
 		var err error
 		if keyPairFlags.sshFormat {
-			result, err = anbuCrypto.GenerateSSHKeyPair(keyDir, keyName, keyPairFlags.keySize)
+			if !cmd.Flags().Changed("comment") {
+				opts.Comment = anbuCrypto.DefaultKeyComment()
+			}
+			result, err = anbuCrypto.GenerateSSHKeyPair(keyDir, keyName, opts)
 		} else {
-			result, err = anbuCrypto.GenerateKeyPair(keyDir, keyName, keyPairFlags.keySize)
+			result, err = anbuCrypto.GenerateKeyPair(keyDir, keyName, opts)
 		}
 		if err != nil {
 			u.PrintFatal("key pair generation failed", err)
 		}
 		u.LineBreak()
 		if keyPairFlags.sshFormat {
-			u.PrintGeneric(fmt.Sprintf("SSH key pair (%d bits) generated", result.KeySize))
+			u.PrintGeneric(fmt.Sprintf("SSH %s key pair (%d bits) generated", result.Type, result.KeySize))
 		} else {
-			u.PrintGeneric(fmt.Sprintf("RSA key pair (%d bits) generated", result.KeySize))
+			u.PrintGeneric(fmt.Sprintf("%s key pair (%d bits) generated", strings.ToUpper(result.Type), result.KeySize))
+		}
+		u.PrintGeneric(fmt.Sprintf("Fingerprint: %s", u.FInfo(result.Fingerprint)))
+		if opts.Passphrase != "" {
+			u.PrintGeneric(fmt.Sprintf("Private key is %s", u.FSuccess("passphrase protected")))
 		}
 		u.PrintGeneric(fmt.Sprintf("Public key: %s", u.FInfo(result.PublicKeyPath)))
 		u.PrintGeneric(fmt.Sprintf("Private key: %s", u.FInfo(result.PrivateKeyPath)))
 	},
 }
 
+var keyChangePassphraseCmd = &cobra.Command{
+	Use:   "change-passphrase <file>",
+	Short: "Add, change or remove the passphrase of a private key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		keyPath := args[0]
+		encrypted, err := anbuCrypto.KeyFileEncrypted(keyPath)
+		if err != nil {
+			u.PrintFatal("failed to read key", err)
+		}
+		oldPassphrase := flagPassphrase(changePassphraseFlags.oldPassphrase, changePassphraseFlags.oldPassphraseFile)
+		if encrypted && oldPassphrase == "" {
+			if oldPassphrase, err = u.PromptPassword("Current passphrase:"); err != nil {
+				u.PrintFatal("failed to read passphrase", err)
+			}
+		}
+		newPassphrase := flagPassphrase(changePassphraseFlags.newPassphrase, changePassphraseFlags.newPassphraseFile)
+		if !changePassphraseFlags.remove && newPassphrase == "" {
+			newPassphrase = promptNewPassword("New passphrase")
+		}
+		if err := anbuCrypto.ChangeKeyPassphrase(keyPath, oldPassphrase, newPassphrase); err != nil {
+			u.PrintFatal("failed to change passphrase", err)
+		}
+		if newPassphrase == "" {
+			u.PrintSuccess(fmt.Sprintf("Passphrase removed from %s", keyPath))
+		} else {
+			u.PrintSuccess(fmt.Sprintf("Passphrase updated for %s", keyPath))
+		}
+	},
+}
+
 func init() {
+	KeyPairCmd.AddCommand(keyChangePassphraseCmd)
+
 	KeyPairCmd.Flags().StringVarP(&keyPairFlags.outputPath, "output-path", "o", "./anbu-key", "Output path and name for the key files")
+	KeyPairCmd.Flags().StringVarP(&keyPairFlags.keyType, "type", "t", anbuCrypto.KeyTypeRSA, fmt.Sprintf("Key type (%s)", strings.Join(anbuCrypto.SupportedKeyTypes, ", ")))
 	KeyPairCmd.Flags().IntVarP(&keyPairFlags.keySize, "key-size", "k", 2048, "RSA key size (e.g., 2048, 3072, 4096)")
-	KeyPairCmd.Flags().BoolVarP(&keyPairFlags.sshFormat, "ssh", "s", false, "Generate keys in SSH format instead of PEM")
+	KeyPairCmd.Flags().BoolVarP(&keyPairFlags.sshFormat, "ssh", "s", false, "Generate keys in OpenSSH format instead of PEM")
+	KeyPairCmd.Flags().StringVarP(&keyPairFlags.comment, "comment", "C", "", "Comment for SSH keys (defaults to user@host)")
+	KeyPairCmd.Flags().BoolVar(&keyPairFlags.pkcs8, "pkcs8", false, "Write PEM private keys as PKCS#8 instead of PKCS#1/SEC 1")
+	KeyPairCmd.Flags().StringVarP(&keyPairFlags.passphrase, "passphrase", "p", "", passphraseUsage("Encrypt the private key with this passphrase"))
+	KeyPairCmd.Flags().StringVar(&keyPairFlags.passphraseFile, "passphrase-file", "", "Encrypt the private key with the passphrase in this file")
+	KeyPairCmd.Flags().BoolVarP(&keyPairFlags.encrypt, "encrypt", "e", false, "Prompt for a passphrase to encrypt the private key")
+	KeyPairCmd.MarkFlagsMutuallyExclusive("passphrase", "passphrase-file", "encrypt")
+
+	keyChangePassphraseCmd.Flags().StringVar(&changePassphraseFlags.oldPassphrase, "old-passphrase", "", passphraseUsage("Current passphrase"))
+	keyChangePassphraseCmd.Flags().StringVar(&changePassphraseFlags.oldPassphraseFile, "old-passphrase-file", "", "Read the current passphrase from a file (prompted for if neither is given)")
+	keyChangePassphraseCmd.Flags().StringVarP(&changePassphraseFlags.newPassphrase, "passphrase", "p", "", passphraseUsage("New passphrase"))
+	keyChangePassphraseCmd.Flags().StringVar(&changePassphraseFlags.newPassphraseFile, "passphrase-file", "", "Read the new passphrase from a file (prompted for if neither is given)")
+	keyChangePassphraseCmd.Flags().BoolVar(&changePassphraseFlags.remove, "remove", false, "Remove the passphrase and store the key unencrypted")
+	keyChangePassphraseCmd.MarkFlagsMutuallyExclusive("old-passphrase", "old-passphrase-file")
+	keyChangePassphraseCmd.MarkFlagsMutuallyExclusive("passphrase", "passphrase-file", "remove")
 }
@@ -0,0 +1,97 @@
+package cryptoCmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var sshSignFlags struct {
+	caKeyPath      string
+	passphrase     string
+	passphraseFile string
+	keyID          string
+	principals     []string
+	anyPrincipal   bool
+	validity       string
+	host           bool
+	options        []string
+	serial         uint64
+	outputPath     string
+}
+
+var keySSHSignCmd = &cobra.Command{
+	Use:   "ssh-sign <public-key>",
+	Short: "Sign an OpenSSH public key as a user or host certificate",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !sshSignFlags.host && len(sshSignFlags.principals) == 0 && !sshSignFlags.anyPrincipal {
+			u.PrintFatal("user certificates need --principals, or --any-principal to be valid for every user", nil)
+		}
+		result, err := withPassphrase("CA key passphrase", flagPassphrase(sshSignFlags.passphrase, sshSignFlags.passphraseFile), func(passphrase string) (*anbuCrypto.SSHCertResult, error) {
+			return anbuCrypto.SignSSHCertificate(args[0], anbuCrypto.SSHCertOptions{
+				CAKeyPath:    sshSignFlags.caKeyPath,
+				CAPassphrase: passphrase,
+				KeyID:        sshSignFlags.keyID,
+				Principals:   sshSignFlags.principals,
+				AnyPrincipal: sshSignFlags.anyPrincipal,
+				Validity:     sshSignFlags.validity,
+				Host:         sshSignFlags.host,
+				Options:      sshSignFlags.options,
+				Serial:       sshSignFlags.serial,
+				OutputPath:   sshSignFlags.outputPath,
+			})
+		})
+		if err != nil {
+			u.PrintFatal("failed to sign SSH certificate", err)
+		}
+		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(args[0]), u.FInfo(u.StyleSymbols["arrow"]),
+			u.FSuccess(fmt.Sprintf("%s certificate written to %s", result.Type, result.CertPath))))
+		validity := "forever"
+		if !result.Forever {
+			validity = fmt.Sprintf("%s to %s", result.ValidAfter.Format(time.RFC3339), result.ValidBefore.Format(time.RFC3339))
+		}
+		principals := strings.Join(result.Principals, ", ")
+		if principals == "" {
+			principals = "(any)"
+		}
+		table := u.NewTable([]string{"Property", "Value"})
+		table.Rows = append(table.Rows,
+			[]string{"Key ID", result.KeyID},
+			[]string{"Serial", fmt.Sprintf("%d", result.Serial)},
+			[]string{"Principals", principals},
+			[]string{"Valid", validity},
+			[]string{"CA", result.CAFingerprint},
+		)
+		if len(result.Critical) > 0 {
+			table.Rows = append(table.Rows, []string{"Critical Options", strings.Join(result.Critical, ", ")})
+		}
+		if len(result.Extensions) > 0 {
+			table.Rows = append(table.Rows, []string{"Extensions", strings.Join(result.Extensions, ", ")})
+		}
+		table.PrintTable(false)
+	},
+}
+
+func init() {
+	KeyPairCmd.AddCommand(keySSHSignCmd)
+
+	keySSHSignCmd.Flags().StringVar(&sshSignFlags.caKeyPath, "ca", "", "Private key of the SSH certificate authority")
+	keySSHSignCmd.Flags().StringVarP(&sshSignFlags.passphrase, "passphrase", "p", "", passphraseUsage("Passphrase of an encrypted CA key"))
+	keySSHSignCmd.Flags().StringVar(&sshSignFlags.passphraseFile, "passphrase-file", "", "Read the CA key passphrase from a file (prompted for if needed)")
+	keySSHSignCmd.Flags().StringVarP(&sshSignFlags.keyID, "id", "I", "", "Key ID recorded in the certificate (defaults to the key comment)")
+	keySSHSignCmd.Flags().StringSliceVarP(&sshSignFlags.principals, "principals", "n", nil, "Users or hostnames the certificate is valid for (comma-separated)")
+	keySSHSignCmd.Flags().BoolVar(&sshSignFlags.anyPrincipal, "any-principal", false, "Allow a user certificate without principals, valid for every user")
+	keySSHSignCmd.Flags().StringVarP(&sshSignFlags.validity, "validity", "V", "24h", "Validity period (e.g., 8h, 30d, 52w or forever)")
+	keySSHSignCmd.Flags().BoolVar(&sshSignFlags.host, "host", false, "Create a host certificate instead of a user certificate")
+	keySSHSignCmd.Flags().StringArrayVarP(&sshSignFlags.options, "option", "O", nil, "Certificate option as in ssh-keygen -O (repeatable)")
+	keySSHSignCmd.Flags().Uint64Var(&sshSignFlags.serial, "serial", 0, "Certificate serial number (random when 0)")
+	keySSHSignCmd.Flags().StringVarP(&sshSignFlags.outputPath, "output", "o", "", "Output path (defaults to <key>-cert.pub)")
+	keySSHSignCmd.MarkFlagRequired("ca")
+	keySSHSignCmd.MarkFlagsMutuallyExclusive("passphrase", "passphrase-file")
+	keySSHSignCmd.MarkFlagsMutuallyExclusive("principals", "any-principal")
+}
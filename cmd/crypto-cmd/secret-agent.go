@@ -0,0 +1,120 @@
+package cryptoCmd
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var secretsAgentFlags struct {
+	ttl    time.Duration
+	socket string
+}
+
+func lockSecretsAgent() bool {
+	socketPath, err := anbuCrypto.SecretsAgentSocket(secretsFlags.secretsFile)
+	if err != nil {
+		return false
+	}
+	return anbuCrypto.LockSecretsAgent(socketPath) == nil
+}
+
+var secretsUnlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Start a local agent that holds the derived key so later commands don't prompt",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		initSecretsStore()
+		if secretsAgentFlags.ttl <= 0 {
+			u.PrintFatal("ttl must be positive", nil)
+		}
+		key, err := anbuCrypto.UnlockSecretsStore(secretsFlags.secretsFile, secretsPassword())
+		if err != nil {
+			u.PrintFatal("failed to unlock secrets store", err)
+		}
+		socketPath, err := anbuCrypto.SecretsAgentSocket(secretsFlags.secretsFile)
+		if err != nil {
+			u.PrintFatal("failed to resolve agent socket", err)
+		}
+		anbuCrypto.LockSecretsAgent(socketPath)
+		executable, err := os.Executable()
+		if err != nil {
+			u.PrintFatal("failed to locate anbu executable", err)
+		}
+		agent := exec.Command(executable, "pass", "agent", "--socket", socketPath, "--ttl", secretsAgentFlags.ttl.String())
+		stdin, err := agent.StdinPipe()
+		if err != nil {
+			u.PrintFatal("failed to create agent pipe", err)
+		}
+		if err := agent.Start(); err != nil {
+			u.PrintFatal("failed to start agent", err)
+		}
+		fmt.Fprintln(stdin, base64.StdEncoding.EncodeToString(key))
+		stdin.Close()
+		clear(key)
+		agent.Process.Release()
+		for range 20 {
+			if _, err := anbuCrypto.RequestAgentKey(socketPath); err == nil {
+				u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(secretsFlags.secretsFile), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess(fmt.Sprintf("Unlocked for %s", secretsAgentFlags.ttl))))
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		u.PrintFatal("agent did not start in time", nil)
+	},
+}
+
+var secretsLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Stop the unlock agent and wipe the key it holds",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		initSecretsStore()
+		if !lockSecretsAgent() {
+			u.PrintInfo("No agent running")
+			return
+		}
+		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(secretsFlags.secretsFile), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess("Locked")))
+	},
+}
+
+var secretsAgentCmd = &cobra.Command{
+	Use:    "agent",
+	Short:  "Run the unlock agent in the foreground (key is read from stdin)",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		signal.Ignore(syscall.SIGHUP)
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			u.PrintFatal("failed to read key", err)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(line))
+		if err != nil {
+			u.PrintFatal("failed to decode key", err)
+		}
+		if err := anbuCrypto.RunSecretsAgent(secretsAgentFlags.socket, key, secretsAgentFlags.ttl); err != nil {
+			u.PrintFatal("agent failed", err)
+		}
+	},
+}
+
+func init() {
+	secretsUnlockCmd.Flags().DurationVar(&secretsAgentFlags.ttl, "ttl", 15*time.Minute, "How long the agent keeps the key before wiping it")
+	secretsAgentCmd.Flags().DurationVar(&secretsAgentFlags.ttl, "ttl", 15*time.Minute, "How long the agent keeps the key before wiping it")
+	secretsAgentCmd.Flags().StringVar(&secretsAgentFlags.socket, "socket", "", "Unix socket path to listen on")
+	secretsAgentCmd.MarkFlagRequired("socket")
+	SecretsCmd.AddCommand(secretsUnlockCmd)
+	SecretsCmd.AddCommand(secretsLockCmd)
+	SecretsCmd.AddCommand(secretsAgentCmd)
+}
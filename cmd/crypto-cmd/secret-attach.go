@@ -0,0 +1,52 @@
+package cryptoCmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var secretsExtractFlags struct {
+	output string
+}
+
+var secretsAttachCmd = &cobra.Command{
+	Use:   "attach <secret-id> <file-path>",
+	Short: "Encrypt a file of any type (kubeconfig, .p12, key files) into the store, streamed in chunks",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		initSecretsStore()
+		info, err := anbuCrypto.AttachFile(secretsFlags.secretsFile, args[0], args[1], nil, secretsKey())
+		if err != nil {
+			u.PrintFatal("failed to attach file", err)
+		}
+		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(args[0]), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess(fmt.Sprintf("Attached %s (%d bytes)", info.Name, info.Size))))
+	},
+}
+
+var secretsExtractCmd = &cobra.Command{
+	Use:   "extract <secret-id>",
+	Short: "Decrypt a file attachment to disk with 0600 permissions (or stdout with -o -)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initSecretsStore()
+		if secretsExtractFlags.output == "" {
+			u.PrintFatal("an output path is required (-o <file> or -o - for stdout)", nil)
+		}
+		info, err := anbuCrypto.ExtractFile(secretsFlags.secretsFile, args[0], secretsExtractFlags.output, secretsKey())
+		if err != nil {
+			u.PrintFatal("failed to extract file", err)
+		}
+		if secretsExtractFlags.output != "-" {
+			u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(secretsExtractFlags.output), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess(fmt.Sprintf("Extracted %s (%d bytes)", info.Name, info.Size))))
+		}
+	},
+}
+
+func init() {
+	secretsExtractCmd.Flags().StringVarP(&secretsExtractFlags.output, "output", "o", "", "Output file path, or - for stdout")
+	SecretsCmd.AddCommand(secretsAttachCmd)
+	SecretsCmd.AddCommand(secretsExtractCmd)
+}
@@ -0,0 +1,49 @@
+package cryptoCmd
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var secretsAuditFlags struct {
+	hibp       string
+	minEntropy float64
+}
+
+var secretsAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Check stored secrets offline for low entropy, common patterns, reuse and optional HIBP breach data",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		initSecretsStore()
+		findings, total, err := anbuCrypto.AuditSecrets(secretsFlags.secretsFile, secretsKey(), secretsAuditFlags.minEntropy, secretsAuditFlags.hibp)
+		if err != nil {
+			u.PrintFatal("failed to audit secrets", err)
+		}
+		if len(findings) == 0 {
+			u.PrintSuccess(fmt.Sprintf("No issues found in %d secrets", total))
+			return
+		}
+		table := u.NewTable([]string{"Secret", "Entropy", "Issues"})
+		for _, finding := range findings {
+			entropy := "-"
+			if !math.IsInf(finding.Entropy, 1) {
+				entropy = fmt.Sprintf("%.0f bits", finding.Entropy)
+			}
+			table.Rows = append(table.Rows, []string{finding.ID, entropy, strings.Join(finding.Issues, "; ")})
+		}
+		table.PrintTable(false)
+		u.PrintWarn(fmt.Sprintf("%d of %d secrets flagged", len(findings), total), nil)
+	},
+}
+
+func init() {
+	secretsAuditCmd.Flags().StringVar(&secretsAuditFlags.hibp, "hibp", "", "Local HIBP data: a directory of range files, one range file named by its prefix (e.g., 5BAA6.txt) or a HASH:COUNT file")
+	secretsAuditCmd.Flags().Float64Var(&secretsAuditFlags.minEntropy, "min-entropy", anbuCrypto.DefaultAuditMinEntropy, "Flag secrets with an estimated entropy below this many bits")
+	SecretsCmd.AddCommand(secretsAuditCmd)
+}
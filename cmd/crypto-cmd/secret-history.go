@@ -0,0 +1,80 @@
+package cryptoCmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var secretsHistoryFlags struct {
+	reveal bool
+}
+
+var secretsHistoryCmd = &cobra.Command{
+	Use:   "history <secret-id>",
+	Short: "Show previous versions of a secret (values only with --reveal)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initSecretsStore()
+		var key []byte
+		if secretsHistoryFlags.reveal {
+			key = secretsKey()
+		}
+		items, err := anbuCrypto.GetSecretHistory(secretsFlags.secretsFile, args[0], key)
+		if err != nil {
+			u.PrintFatal("failed to get secret history", err)
+		}
+		if len(items) == 0 {
+			u.PrintInfo("No previous versions found")
+			return
+		}
+		headers := []string{"#", "Type", "Set", "Replaced"}
+		if secretsHistoryFlags.reveal {
+			headers = append(headers, "Value")
+		}
+		table := u.NewTable(headers)
+		for _, item := range items {
+			kind := item.Kind
+			if kind == "" {
+				kind = "value"
+			}
+			row := []string{
+				fmt.Sprintf("%d", item.Index),
+				kind,
+				secretAge(item.SetAt),
+				secretAge(item.ReplacedAt),
+			}
+			if secretsHistoryFlags.reveal {
+				row = append(row, item.Value)
+			}
+			table.Rows = append(table.Rows, row)
+		}
+		table.PrintTable(false)
+	},
+}
+
+var secretsRollbackCmd = &cobra.Command{
+	Use:   "rollback <secret-id> <n>",
+	Short: "Restore version n from the history of a secret (the current value is kept in history)",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		initSecretsStore()
+		index, err := strconv.Atoi(args[1])
+		if err != nil {
+			u.PrintFatal("invalid version number", nil)
+		}
+		if err := anbuCrypto.RollbackSecret(secretsFlags.secretsFile, args[0], index, secretsKey()); err != nil {
+			u.PrintFatal("failed to roll back secret", err)
+		}
+		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(args[0]), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess(fmt.Sprintf("Rolled back to version %d", index))))
+	},
+}
+
+func init() {
+	secretsHistoryCmd.Flags().BoolVarP(&secretsHistoryFlags.reveal, "reveal", "r", false, "Decrypt and show previous values")
+	SecretsCmd.AddCommand(secretsHistoryCmd)
+	SecretsCmd.AddCommand(secretsRollbackCmd)
+}
@@ -0,0 +1,28 @@
+package cryptoCmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var secretsOTPCmd = &cobra.Command{
+	Use:   "otp <secret-id>",
+	Short: "Print the current TOTP code for a secret holding an otpauth URI or base32 seed",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initSecretsStore()
+		code, remaining, err := anbuCrypto.GenerateTOTP(secretsFlags.secretsFile, args[0], secretsKey())
+		if err != nil {
+			u.PrintFatal("failed to generate code", err)
+		}
+		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FSuccess(code), u.FInfo(u.StyleSymbols["arrow"]), u.FDebug(fmt.Sprintf("valid for %s", remaining.Round(time.Second)))))
+	},
+}
+
+func init() {
+	SecretsCmd.AddCommand(secretsOTPCmd)
+}
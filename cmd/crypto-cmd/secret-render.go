@@ -0,0 +1,55 @@
+package cryptoCmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var secretsRenderFlags struct {
+	output string
+	check  bool
+}
+
+var secretsRenderCmd = &cobra.Command{
+	Use:   "render <template-file>",
+	Short: "Render a Go template with {{ secret \"NAME\" }} placeholders into a 0600 file (or stdout)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initSecretsStore()
+		templateFile := args[0]
+		if secretsRenderFlags.check {
+			refs, missing, err := anbuCrypto.MissingTemplateSecrets(secretsFlags.secretsFile, templateFile)
+			if err != nil {
+				u.PrintFatal("failed to check template", err)
+			}
+			if len(missing) == 0 {
+				u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(templateFile), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess(fmt.Sprintf("All %d referenced secrets exist", len(refs)))))
+				return
+			}
+			table := u.NewTable([]string{"Missing Secret"})
+			for _, name := range missing {
+				table.Rows = append(table.Rows, []string{name})
+			}
+			table.PrintTable(false)
+			u.PrintError(fmt.Sprintf("%d of %d referenced secrets are missing", len(missing), len(refs)), nil)
+			os.Exit(1)
+		}
+		count, err := anbuCrypto.RenderSecretsTemplate(secretsFlags.secretsFile, templateFile, secretsRenderFlags.output, secretsKey())
+		if err != nil {
+			u.PrintFatal("failed to render template", err)
+		}
+		if secretsRenderFlags.output != "" {
+			u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(secretsRenderFlags.output), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess(fmt.Sprintf("Rendered with %d secrets", count))))
+		}
+	},
+}
+
+func init() {
+	secretsRenderCmd.Flags().StringVarP(&secretsRenderFlags.output, "output", "o", "", "Output file (written with 0600 permissions, stdout if not set)")
+	secretsRenderCmd.Flags().BoolVar(&secretsRenderFlags.check, "check", false, "List referenced secrets missing from the store without decrypting anything")
+	SecretsCmd.AddCommand(secretsRenderCmd)
+}
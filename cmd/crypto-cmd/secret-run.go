@@ -0,0 +1,60 @@
+package cryptoCmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var secretsRunFlags struct {
+	env  []string
+	mask bool
+}
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var secretsRunCmd = &cobra.Command{
+	Use:   "run --env VAR[=secret-id] -- <command> [args...]",
+	Short: "Run a command with secrets injected as environment variables of the child process only",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initSecretsStore()
+		if len(secretsRunFlags.env) == 0 {
+			u.PrintFatal("at least one --env mapping is required", nil)
+		}
+		key := secretsKey()
+		env := make(map[string]string)
+		for _, mapping := range secretsRunFlags.env {
+			name, secretID, found := strings.Cut(mapping, "=")
+			if !found {
+				secretID = name
+			}
+			if !envNamePattern.MatchString(name) || secretID == "" {
+				u.PrintFatal(fmt.Sprintf("invalid env mapping '%s'", mapping), nil)
+			}
+			value, err := anbuCrypto.GetSecret(secretsFlags.secretsFile, secretID, key)
+			if err != nil {
+				u.PrintFatal(fmt.Sprintf("failed to get secret for %s", name), err)
+			}
+			env[name] = value
+		}
+		clear(key)
+		code, err := anbuCrypto.RunWithSecrets(args, env, secretsRunFlags.mask)
+		if err != nil {
+			u.PrintFatal("failed to run command", err)
+		}
+		os.Exit(code)
+	},
+}
+
+func init() {
+	secretsRunCmd.Flags().SetInterspersed(false)
+	secretsRunCmd.Flags().StringArrayVarP(&secretsRunFlags.env, "env", "e", nil, "Environment mapping VAR=secret-id (or VAR to use a secret of the same name)")
+	secretsRunCmd.Flags().BoolVar(&secretsRunFlags.mask, "mask", false, "Mask secret values that appear in the command's stdout/stderr")
+	SecretsCmd.AddCommand(secretsRunCmd)
+}
@@ -0,0 +1,114 @@
+package cryptoCmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var secretsShamirFlags struct {
+	shares    int
+	threshold int
+	outDir    string
+	store     string
+}
+
+var secretsSplitCmd = &cobra.Command{
+	Use:   "split <secret-id>",
+	Short: "Split a secret into Shamir shares so that any threshold of them can recover it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initSecretsStore()
+		secretID := args[0]
+		shares, err := anbuCrypto.SplitSecret(secretsFlags.secretsFile, secretID, secretsShamirFlags.shares, secretsShamirFlags.threshold, secretsKey())
+		if err != nil {
+			u.PrintFatal("failed to split secret", err)
+		}
+		if secretsShamirFlags.outDir == "" {
+			for _, share := range shares {
+				u.PrintGeneric(share)
+			}
+			return
+		}
+		if err := os.MkdirAll(secretsShamirFlags.outDir, 0700); err != nil {
+			u.PrintFatal("failed to create output directory", err)
+		}
+		for i, share := range shares {
+			sharePath := filepath.Join(secretsShamirFlags.outDir, fmt.Sprintf("%s.share-%d", secretID, i+1))
+			if err := os.WriteFile(sharePath, []byte(share+"\n"), 0600); err != nil {
+				u.PrintFatal("failed to write share", err)
+			}
+			u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(sharePath), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess(fmt.Sprintf("Share %d of %d", i+1, len(shares)))))
+		}
+	},
+}
+
+var secretsCombineCmd = &cobra.Command{
+	Use:   "combine [share-or-file...]",
+	Short: "Reconstruct a secret from Shamir shares and print it or store it with --store",
+	Run: func(cmd *cobra.Command, args []string) {
+		var shares []string
+		for _, arg := range args {
+			if data, err := os.ReadFile(arg); err == nil {
+				arg = string(data)
+			}
+			shares = append(shares, strings.TrimSpace(arg))
+		}
+		if len(shares) == 0 {
+			shares = promptShares()
+		}
+		if secretsShamirFlags.store == "" {
+			value, err := anbuCrypto.CombineSecretShares(shares)
+			if err != nil {
+				u.PrintFatal("failed to combine shares", err)
+			}
+			u.PrintGeneric(string(value))
+			return
+		}
+		initSecretsStore()
+		if err := anbuCrypto.CombineSecret(secretsFlags.secretsFile, secretsShamirFlags.store, shares, secretsKey()); err != nil {
+			u.PrintFatal("failed to store the recovered secret", err)
+		}
+		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(secretsShamirFlags.store), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess("Secret recovered and stored")))
+	},
+}
+
+// promptShares reads shares one at a time until the threshold in the first share is reached
+func promptShares() []string {
+	var shares []string
+	threshold := 0
+	for threshold == 0 || len(shares) < threshold {
+		label := fmt.Sprintf("Share %d:", len(shares)+1)
+		if threshold > 0 {
+			label = fmt.Sprintf("Share %d of %d:", len(shares)+1, threshold)
+		}
+		share, err := u.PromptInput(label, "anbu-share:v1:...")
+		if err != nil {
+			u.PrintFatal("failed to read share", err)
+		}
+		if share == "" {
+			u.PrintFatal("no share provided", nil)
+		}
+		parsed, err := anbuCrypto.ParseSecretShare(share)
+		if err != nil {
+			u.PrintFatal("invalid share", err)
+		}
+		threshold = parsed.Threshold
+		shares = append(shares, share)
+	}
+	return shares
+}
+
+func init() {
+	secretsSplitCmd.Flags().IntVarP(&secretsShamirFlags.shares, "shares", "n", 5, "Number of shares to create")
+	secretsSplitCmd.Flags().IntVarP(&secretsShamirFlags.threshold, "threshold", "k", 3, "Number of shares required to recover the secret")
+	secretsSplitCmd.Flags().StringVarP(&secretsShamirFlags.outDir, "out-dir", "o", "", "Write each share to <secret-id>.share-N in this directory instead of printing")
+	secretsCombineCmd.Flags().StringVar(&secretsShamirFlags.store, "store", "", "Store the recovered value under this secret ID instead of printing it")
+	SecretsCmd.AddCommand(secretsSplitCmd)
+	SecretsCmd.AddCommand(secretsCombineCmd)
+}
@@ -3,7 +3,8 @@ package cryptoCmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
@@ -18,24 +19,108 @@ var SecretsCmd = &cobra.Command{
 
 var secretsFlags struct {
 	secretsFile string
+	vault       string
 	multiline   bool
+	totp        bool
 	password    string
+	kdf         string
+	tag         string
+	tags        []string
+	note        string
+	encrypt     bool
+	format      string
+	onConflict  string
+	dryRun      bool
 	initialized bool
 }
 
+func secretsPassword() string {
+	if secretsFlags.password != "" {
+		return secretsFlags.password
+	}
+	password, err := u.PromptPassword("Master password:")
+	if err != nil {
+		u.PrintFatal("failed to read password", err)
+	}
+	if password == "" {
+		u.PrintFatal("no password provided", nil)
+	}
+	return password
+}
+
+func secretsNewPassword() string {
+	return promptNewPassword("New password")
+}
+
+func promptNewPassword(label string) string {
+	password, err := u.PromptPassword(label + ":")
+	if err != nil {
+		u.PrintFatal("failed to read password", err)
+	}
+	if password == "" {
+		u.PrintFatal("no password provided", nil)
+	}
+	confirm, err := u.PromptPassword("Confirm " + strings.ToLower(label) + ":")
+	if err != nil {
+		u.PrintFatal("failed to read password", err)
+	}
+	if confirm != password {
+		u.PrintFatal("passwords do not match", nil)
+	}
+	return password
+}
+
+func agentKey(filePath string) []byte {
+	socketPath, err := anbuCrypto.SecretsAgentSocket(filePath)
+	if err != nil {
+		return nil
+	}
+	key, err := anbuCrypto.RequestAgentKey(socketPath)
+	if err != nil {
+		return nil
+	}
+	return key
+}
+
+func secretsKey() []byte {
+	if secretsFlags.password == "" {
+		if key := agentKey(secretsFlags.secretsFile); key != nil {
+			return key
+		}
+	}
+	key, err := anbuCrypto.UnlockSecretsStore(secretsFlags.secretsFile, secretsPassword())
+	if err != nil {
+		u.PrintFatal("failed to unlock secrets store", err)
+	}
+	return key
+}
+
+func secretsVaultName() string {
+	if secretsFlags.vault != "" {
+		return secretsFlags.vault
+	}
+	if vault := os.Getenv("ANBU_VAULT"); vault != "" {
+		return vault
+	}
+	return anbuCrypto.DefaultSecretsVault
+}
+
 func initSecretsStore() {
 	if secretsFlags.initialized {
 		return
 	}
 	secretsFlags.initialized = true
-	homeDir, err := os.UserHomeDir()
-	secretsFlags.secretsFile = "secrets.json"
-	if err == nil {
-		anbuDir := filepath.Join(homeDir, ".config", "anbu")
-		if err := os.MkdirAll(anbuDir, 0755); err != nil {
-			u.PrintFatal("failed to create anbu directory", err)
+	vault := secretsVaultName()
+	filePath, err := anbuCrypto.SecretsVaultPath(vault)
+	if err != nil {
+		u.PrintFatal("failed to resolve vault", err)
+	}
+	secretsFlags.secretsFile = filePath
+	if vault != anbuCrypto.DefaultSecretsVault {
+		if _, err := os.Stat(filePath); err != nil {
+			u.PrintFatal(fmt.Sprintf("vault '%s' does not exist, create it with 'anbu pass vault create %s'", vault, vault), nil)
 		}
-		secretsFlags.secretsFile = filepath.Join(anbuDir, "secrets.json")
+		return
 	}
 	if err := anbuCrypto.InitializeSecretsStore(secretsFlags.secretsFile); err != nil {
 		u.PrintFatal("failed to initialize secrets store", err)
@@ -44,35 +129,54 @@ func initSecretsStore() {
 
 var secretsListCmd = &cobra.Command{
 	Use:   "list",
-	Short: "List all secrets with their IDs",
+	Short: "List all secrets with their tags and ages, optionally filtered by tag",
 	Run: func(cmd *cobra.Command, args []string) {
 		initSecretsStore()
-		secrets, err := anbuCrypto.ListSecrets(secretsFlags.secretsFile)
+		secrets, err := anbuCrypto.ListSecrets(secretsFlags.secretsFile, secretsFlags.tag)
 		if err != nil {
 			u.PrintFatal("failed to list secrets", err)
 		}
+		if info, err := anbuCrypto.GetSecretsStoreInfo(secretsFlags.secretsFile); err == nil && info.Version < 2 {
+			u.PrintWarn("secrets store uses the legacy v1 format, run 'anbu pass migrate' to upgrade", nil)
+		}
 		if len(secrets) == 0 {
 			u.PrintInfo("No secrets found")
 			return
 		}
-		table := u.NewTable([]string{"#", "Name"})
-		for i, id := range secrets {
+		table := u.NewTable([]string{"#", "Name", "Type", "Tags", "Updated", "Versions", "Note"})
+		for i, secret := range secrets {
+			kind := secret.Kind
+			if kind == "" {
+				kind = "value"
+			}
 			table.Rows = append(table.Rows, []string{
 				fmt.Sprintf("%d", i+1),
-				id,
+				secret.ID,
+				kind,
+				strings.Join(secret.Tags, ", "),
+				secretAge(secret.UpdatedAt),
+				fmt.Sprintf("%d", secret.Versions),
+				secret.Note,
 			})
 		}
 		table.PrintTable(false)
 	},
 }
+
+func secretAge(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return u.FormatTimeAgo(time.Since(t))
+}
+
 var secretsGetCmd = &cobra.Command{
 	Use:   "get <secret-id>",
 	Short: "Print the decrypted value of a specific secret",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		initSecretsStore()
-		password := secretsFlags.password
-		value, err := anbuCrypto.GetSecret(secretsFlags.secretsFile, args[0], password)
+		value, err := anbuCrypto.GetSecret(secretsFlags.secretsFile, args[0], secretsKey())
 		if err != nil {
 			u.PrintFatal("failed to get secret", err)
 		}
@@ -81,14 +185,16 @@ var secretsGetCmd = &cobra.Command{
 }
 var secretsSetCmd = &cobra.Command{
 	Use:   "add <secret-id>",
-	Short: "Set the value for a secret with optional multiline input",
+	Short: "Set the value for a secret with optional multiline input or a TOTP seed",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		initSecretsStore()
 		secretID := args[0]
 		var value string
 		var err error
-		if secretsFlags.multiline {
+		if secretsFlags.totp {
+			value, err = u.PromptInput(fmt.Sprintf("Enter otpauth URI or base32 seed for '%s':", secretID), "")
+		} else if secretsFlags.multiline {
 			value, err = u.PromptTextArea(fmt.Sprintf("Enter value for secret '%s':", secretID), "")
 		} else {
 			value, err = u.PromptInput(fmt.Sprintf("Enter value for secret '%s':", secretID), "")
@@ -99,8 +205,16 @@ var secretsSetCmd = &cobra.Command{
 		if value == "" {
 			u.PrintFatal("no value provided for secret", nil)
 		}
-		password := secretsFlags.password
-		if err := anbuCrypto.SetSecret(secretsFlags.secretsFile, secretID, value, password); err != nil {
+		meta := &anbuCrypto.SecretMetadata{Note: secretsFlags.note}
+		if cmd.Flags().Changed("tag") {
+			meta.Tags = secretsFlags.tags
+		}
+		if secretsFlags.totp {
+			err = anbuCrypto.SetTOTPSecret(secretsFlags.secretsFile, secretID, value, meta, secretsKey())
+		} else {
+			err = anbuCrypto.SetSecret(secretsFlags.secretsFile, secretID, value, meta, secretsKey())
+		}
+		if err != nil {
 			u.PrintFatal("failed to set secret", err)
 		}
 		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(secretID), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess("Secret set")))
@@ -112,7 +226,7 @@ var secretsDeleteCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		initSecretsStore()
-		if err := anbuCrypto.DeleteSecret(secretsFlags.secretsFile, args[0]); err != nil {
+		if err := anbuCrypto.DeleteSecret(secretsFlags.secretsFile, args[0], secretsKey()); err != nil {
 			u.PrintFatal("failed to delete secret", err)
 		}
 		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(args[0]), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess("Secret deleted")))
@@ -120,40 +234,163 @@ var secretsDeleteCmd = &cobra.Command{
 }
 var secretsImportCmd = &cobra.Command{
 	Use:   "import <file-path>",
-	Short: "Import secrets from a JSON file and encrypt them in the store",
+	Short: "Import secrets from a JSON, dotenv, CSV or password manager export, or an encrypted bundle",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		initSecretsStore()
 		importFile := args[0]
-		password := secretsFlags.password
-		if err := anbuCrypto.ImportSecrets(secretsFlags.secretsFile, importFile, password); err != nil {
+		var records []anbuCrypto.SecretRecord
+		var issues []anbuCrypto.FormatIssue
+		var err error
+		if anbuCrypto.IsSecretsBundle(importFile) {
+			passphrase, perr := u.PromptPassword("Transfer passphrase:")
+			if perr != nil {
+				u.PrintFatal("failed to read passphrase", perr)
+			}
+			records, _, err = anbuCrypto.ReadSecretsBundle(importFile, passphrase)
+		} else {
+			records, issues, err = anbuCrypto.ReadSecretsFile(importFile, secretsFlags.format)
+		}
+		if err != nil {
+			u.PrintFatal("failed to read import file", err)
+		}
+		printFormatIssues(issues, "skipped")
+		var key []byte
+		if !secretsFlags.dryRun {
+			key = secretsKey()
+		}
+		results, err := anbuCrypto.ImportSecrets(secretsFlags.secretsFile, records, secretsFlags.onConflict, secretsFlags.dryRun, key)
+		if err != nil {
 			u.PrintFatal("failed to import secrets", err)
 		}
-		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(importFile), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess("Secrets imported")))
+		if secretsFlags.dryRun {
+			table := u.NewTable([]string{"Secret", "Action", "Stored As"})
+			for _, result := range results {
+				storedAs := result.TargetID
+				if result.Action == "skip" {
+					storedAs = "-"
+				}
+				table.Rows = append(table.Rows, []string{result.ID, result.Action, storedAs})
+			}
+			table.PrintTable(false)
+			return
+		}
+		imported := 0
+		for _, result := range results {
+			if result.Action != "skip" {
+				imported++
+			}
+		}
+		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(importFile), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess(fmt.Sprintf("%d of %d secrets imported", imported, len(results)))))
 	},
 }
 var secretsExportCmd = &cobra.Command{
 	Use:   "export <file-path>",
-	Short: "Export all secrets to a JSON file in decrypted form",
+	Short: "Export all secrets in decrypted form (JSON, dotenv, CSV or password manager format) or to an encrypted bundle",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		initSecretsStore()
 		exportFile := args[0]
-		password := secretsFlags.password
-		if err := anbuCrypto.ExportSecrets(secretsFlags.secretsFile, exportFile, password); err != nil {
+		if secretsFlags.encrypt {
+			if cmd.Flags().Changed("format") {
+				u.PrintFatal("--encrypt cannot be combined with --format", nil)
+			}
+			key := secretsKey()
+			count, skipped, err := anbuCrypto.ExportSecretsBundle(secretsFlags.secretsFile, exportFile, promptNewPassword("Transfer passphrase"), key)
+			if err != nil {
+				u.PrintFatal("failed to export secrets", err)
+			}
+			printFormatIssues(skipped, "skipped")
+			u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(exportFile), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess(fmt.Sprintf("%d secrets exported to encrypted bundle", count))))
+			return
+		}
+		issues, err := anbuCrypto.ExportSecrets(secretsFlags.secretsFile, exportFile, secretsFlags.format, secretsKey())
+		if err != nil {
 			u.PrintFatal("failed to export secrets", err)
 		}
+		printFormatIssues(issues, "adjusted or skipped")
 		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(exportFile), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess("Secrets exported")))
 	},
 }
 
+func printFormatIssues(issues []anbuCrypto.FormatIssue, verb string) {
+	if len(issues) == 0 {
+		return
+	}
+	u.PrintWarn(fmt.Sprintf("%d rows %s", len(issues), verb), nil)
+	table := u.NewTable([]string{"Row", "Secret", "Issue"})
+	for _, issue := range issues {
+		id := issue.ID
+		if id == "" {
+			id = "-"
+		}
+		row := "-"
+		if issue.Row > 0 {
+			row = fmt.Sprintf("%d", issue.Row)
+		}
+		table.Rows = append(table.Rows, []string{row, id, issue.Reason})
+	}
+	table.PrintTable(false)
+}
+
+func rekeySecretsStore() {
+	initSecretsStore()
+	oldKey := secretsKey()
+	if err := anbuCrypto.RekeySecretsStore(secretsFlags.secretsFile, oldKey, secretsNewPassword()); err != nil {
+		u.PrintFatal("failed to rekey secrets store", err)
+	}
+	lockSecretsAgent()
+	u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(secretsVaultName()), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess("Store rekeyed with the new password")))
+}
+
+var secretsRekeyCmd = &cobra.Command{
+	Use:   "rekey",
+	Short: "Re-encrypt every secret under a new password in memory and atomically replace the store",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		rekeySecretsStore()
+	},
+}
+
+var secretsMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Re-encrypt the store with a fresh random salt and the selected KDF (upgrades v1 stores)",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		initSecretsStore()
+		if err := anbuCrypto.MigrateSecretsStore(secretsFlags.secretsFile, secretsPassword(), secretsFlags.kdf); err != nil {
+			u.PrintFatal("failed to migrate secrets store", err)
+		}
+		lockSecretsAgent()
+		info, err := anbuCrypto.GetSecretsStoreInfo(secretsFlags.secretsFile)
+		if err != nil {
+			u.PrintFatal("failed to read secrets store", err)
+		}
+		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(info.KDF), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess(fmt.Sprintf("Store migrated to v%d (%d secrets)", info.Version, info.Entries))))
+	},
+}
+
 func init() {
-	SecretsCmd.PersistentFlags().StringVar(&secretsFlags.password, "password", "p455w0rd", "Password for encryption/decryption (default: p455w0rd)")
+	SecretsCmd.PersistentFlags().StringVar(&secretsFlags.vault, "vault", "", "Vault to operate on (falls back to ANBU_VAULT, then the default vault)")
+	SecretsCmd.PersistentFlags().StringVar(&secretsFlags.password, "password", "", "Password for encryption/decryption (falls back to the unlock agent, then a prompt)")
 	secretsSetCmd.Flags().BoolVarP(&secretsFlags.multiline, "multiline", "m", false, "Enable multiline input (end with 'EOF' on a new line)")
+	secretsSetCmd.Flags().BoolVar(&secretsFlags.totp, "totp", false, "Store a TOTP seed (otpauth URI or base32) for use with 'anbu pass otp'")
+	secretsSetCmd.Flags().StringSliceVarP(&secretsFlags.tags, "tag", "t", nil, "Tags for the secret (replaces existing tags)")
+	secretsSetCmd.Flags().StringVarP(&secretsFlags.note, "note", "n", "", "Free-form note for the secret")
+	secretsExportCmd.Flags().BoolVarP(&secretsFlags.encrypt, "encrypt", "e", false, "Write an encrypted bundle protected by a transfer passphrase")
+	secretsFormatsHelp := fmt.Sprintf("File format (%s)", strings.Join(anbuCrypto.SupportedSecretsFormats(), ", "))
+	secretsExportCmd.Flags().StringVarP(&secretsFlags.format, "format", "f", anbuCrypto.DefaultSecretsFormat, secretsFormatsHelp)
+	secretsImportCmd.Flags().StringVarP(&secretsFlags.format, "format", "f", anbuCrypto.DefaultSecretsFormat, secretsFormatsHelp+", ignored for encrypted bundles")
+	secretsImportCmd.Flags().StringVar(&secretsFlags.onConflict, "on-conflict", anbuCrypto.ConflictOverwrite, "Conflict policy for existing IDs (overwrite, skip, rename)")
+	secretsImportCmd.Flags().BoolVar(&secretsFlags.dryRun, "dry-run", false, "Preview what would be imported without changing the store")
+	secretsListCmd.Flags().StringVarP(&secretsFlags.tag, "tag", "t", "", "Only list secrets with this tag")
+	secretsMigrateCmd.Flags().StringVar(&secretsFlags.kdf, "kdf", anbuCrypto.DefaultSecretsKDF, fmt.Sprintf("Key derivation function (%s)", strings.Join(anbuCrypto.SupportedSecretsKDFs(), ", ")))
 	SecretsCmd.AddCommand(secretsListCmd)
 	SecretsCmd.AddCommand(secretsGetCmd)
 	SecretsCmd.AddCommand(secretsSetCmd)
 	SecretsCmd.AddCommand(secretsDeleteCmd)
 	SecretsCmd.AddCommand(secretsImportCmd)
 	SecretsCmd.AddCommand(secretsExportCmd)
+	SecretsCmd.AddCommand(secretsMigrateCmd)
+	SecretsCmd.AddCommand(secretsRekeyCmd)
 }
@@ -0,0 +1,137 @@
+package cryptoCmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var secretsVaultFlags struct {
+	force    bool
+	targetID string
+	to       string
+}
+
+var secretsVaultCmd = &cobra.Command{
+	Use:   "vault",
+	Short: "Manage named vaults, each with its own file and password",
+}
+
+var secretsVaultCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a new named vault protected by its own password",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		password := secretsFlags.password
+		if password == "" {
+			password = secretsNewPassword()
+		}
+		filePath, err := anbuCrypto.CreateSecretsVault(args[0], password)
+		if err != nil {
+			u.PrintFatal("failed to create vault", err)
+		}
+		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(filePath), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess(fmt.Sprintf("Vault '%s' created", args[0]))))
+	},
+}
+
+var secretsVaultListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all vaults with their secret counts and KDFs",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		vaults, err := anbuCrypto.ListSecretsVaults()
+		if err != nil {
+			u.PrintFatal("failed to list vaults", err)
+		}
+		current := secretsVaultName()
+		table := u.NewTable([]string{"Vault", "Secrets", "KDF", "Path"})
+		for _, vault := range vaults {
+			filePath, err := anbuCrypto.SecretsVaultPath(vault)
+			if err != nil {
+				continue
+			}
+			name := vault
+			if vault == current {
+				name += " *"
+			}
+			info, err := anbuCrypto.GetSecretsStoreInfo(filePath)
+			if err != nil {
+				table.Rows = append(table.Rows, []string{name, "-", "-", filePath})
+				continue
+			}
+			table.Rows = append(table.Rows, []string{name, fmt.Sprintf("%d", info.Entries), info.KDF, filePath})
+		}
+		table.PrintTable(false)
+	},
+}
+
+var secretsVaultDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a named vault (non-empty vaults require --force)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := anbuCrypto.DeleteSecretsVault(args[0], secretsVaultFlags.force); err != nil {
+			u.PrintFatal("failed to delete vault", err)
+		}
+		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(args[0]), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess("Vault deleted")))
+	},
+}
+
+var secretsVaultRekeyCmd = &cobra.Command{
+	Use:   "rekey [name]",
+	Short: "Change the password of a vault (defaults to the current vault)",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 0 {
+			secretsFlags.vault = args[0]
+		}
+		rekeySecretsStore()
+	},
+}
+
+var secretsCopyCmd = &cobra.Command{
+	Use:   "copy <secret-id> --to <vault>",
+	Short: "Copy a secret into another vault, re-encrypting it in memory",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initSecretsStore()
+		dstPath, err := anbuCrypto.SecretsVaultPath(secretsVaultFlags.to)
+		if err != nil {
+			u.PrintFatal("failed to resolve destination vault", err)
+		}
+		if dstPath == secretsFlags.secretsFile {
+			u.PrintFatal("source and destination vaults are the same", nil)
+		}
+		srcKey := secretsKey()
+		dstKey := agentKey(dstPath)
+		if dstKey == nil {
+			password, err := u.PromptPassword(fmt.Sprintf("Password for vault '%s':", secretsVaultFlags.to))
+			if err != nil {
+				u.PrintFatal("failed to read password", err)
+			}
+			dstKey, err = anbuCrypto.UnlockSecretsStore(dstPath, password)
+			if err != nil {
+				u.PrintFatal("failed to unlock destination vault", err)
+			}
+		}
+		if err := anbuCrypto.CopySecret(secretsFlags.secretsFile, dstPath, args[0], secretsVaultFlags.targetID, srcKey, dstKey); err != nil {
+			u.PrintFatal("failed to copy secret", err)
+		}
+		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(args[0]), u.FInfo(u.StyleSymbols["arrow"]), u.FSuccess(fmt.Sprintf("Copied to vault '%s'", secretsVaultFlags.to))))
+	},
+}
+
+func init() {
+	secretsVaultDeleteCmd.Flags().BoolVarP(&secretsVaultFlags.force, "force", "f", false, "Delete the vault even if it still holds secrets")
+	secretsCopyCmd.Flags().StringVar(&secretsVaultFlags.to, "to", "", "Destination vault")
+	secretsCopyCmd.Flags().StringVar(&secretsVaultFlags.targetID, "as", "", "Secret ID in the destination vault (defaults to the same ID)")
+	secretsCopyCmd.MarkFlagRequired("to")
+	secretsVaultCmd.AddCommand(secretsVaultCreateCmd)
+	secretsVaultCmd.AddCommand(secretsVaultListCmd)
+	secretsVaultCmd.AddCommand(secretsVaultDeleteCmd)
+	secretsVaultCmd.AddCommand(secretsVaultRekeyCmd)
+	SecretsCmd.AddCommand(secretsVaultCmd)
+	SecretsCmd.AddCommand(secretsCopyCmd)
+}
@@ -0,0 +1,98 @@
+package cryptoCmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var signFlags struct {
+	keyPath        string
+	passphrase     string
+	passphraseFile string
+	format         string
+	namespace      string
+	outputPath     string
+}
+
+var verifyFlags struct {
+	signaturePath  string
+	publicKeyPath  string
+	passphrase     string
+	passphraseFile string
+	namespace      string
+}
+
+var SignCmd = &cobra.Command{
+	Use:   "sign <file>",
+	Short: "Create a detached Ed25519, RSA-PSS, ECDSA or SSH (SSHSIG) signature for a file",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		result, err := withPassphrase("Key passphrase", flagPassphrase(signFlags.passphrase, signFlags.passphraseFile), func(passphrase string) (*anbuCrypto.SignatureResult, error) {
+			return anbuCrypto.SignFile(args[0], anbuCrypto.SignOptions{
+				KeyPath:    signFlags.keyPath,
+				Passphrase: passphrase,
+				Format:     signFlags.format,
+				Namespace:  signFlags.namespace,
+				OutputPath: signFlags.outputPath,
+			})
+		})
+		if err != nil {
+			u.PrintFatal("failed to sign file", err)
+		}
+		u.PrintGeneric(fmt.Sprintf("%s %s %s", u.FDebug(args[0]), u.FInfo(u.StyleSymbols["arrow"]),
+			u.FSuccess(fmt.Sprintf("signature written to %s", result.SignaturePath))))
+		printSignatureResult(result)
+	},
+}
+
+var VerifyCmd = &cobra.Command{
+	Use:   "verify <file>",
+	Short: "Verify a detached raw or SSH (SSHSIG) signature against a public key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		signaturePath := verifyFlags.signaturePath
+		if signaturePath == "" {
+			signaturePath = args[0] + ".sig"
+		}
+		result, err := withPassphrase("Key passphrase", flagPassphrase(verifyFlags.passphrase, verifyFlags.passphraseFile), func(passphrase string) (*anbuCrypto.SignatureResult, error) {
+			return anbuCrypto.VerifyFile(args[0], signaturePath, verifyFlags.publicKeyPath, passphrase, verifyFlags.namespace)
+		})
+		if err != nil {
+			u.PrintFatal("signature verification failed", err)
+		}
+		u.PrintSuccess(fmt.Sprintf("Good signature for %s", args[0]))
+		printSignatureResult(result)
+	},
+}
+
+func printSignatureResult(result *anbuCrypto.SignatureResult) {
+	u.PrintGeneric(fmt.Sprintf("Format: %s", u.FInfo(result.Format)))
+	u.PrintGeneric(fmt.Sprintf("Algorithm: %s", u.FInfo(result.Algorithm)))
+	if result.Namespace != "" {
+		u.PrintGeneric(fmt.Sprintf("Namespace: %s", u.FInfo(result.Namespace)))
+	}
+	u.PrintGeneric(fmt.Sprintf("Key: %s", u.FInfo(result.Fingerprint)))
+}
+
+func init() {
+	SignCmd.Flags().StringVarP(&signFlags.keyPath, "key", "k", "", "Private key to sign with (any format 'anbu kp' produces)")
+	SignCmd.Flags().StringVarP(&signFlags.passphrase, "passphrase", "p", "", passphraseUsage("Passphrase of an encrypted key"))
+	SignCmd.Flags().StringVar(&signFlags.passphraseFile, "passphrase-file", "", "Read the key passphrase from a file (prompted for if needed)")
+	SignCmd.Flags().StringVarP(&signFlags.format, "format", "f", "", fmt.Sprintf("Signature format (%s); defaults to ssh for OpenSSH keys and raw otherwise", strings.Join(anbuCrypto.SignFormats, ", ")))
+	SignCmd.Flags().StringVarP(&signFlags.namespace, "namespace", "n", anbuCrypto.DefaultSignNamespace, "SSHSIG namespace, as in ssh-keygen -Y sign -n")
+	SignCmd.Flags().StringVarP(&signFlags.outputPath, "output", "o", "", "Signature output path (defaults to <file>.sig)")
+	SignCmd.MarkFlagRequired("key")
+	SignCmd.MarkFlagsMutuallyExclusive("passphrase", "passphrase-file")
+
+	VerifyCmd.Flags().StringVarP(&verifyFlags.signaturePath, "sig", "s", "", "Signature file (defaults to <file>.sig)")
+	VerifyCmd.Flags().StringVar(&verifyFlags.publicKeyPath, "pub", "", "Public key of the signer (PEM, OpenSSH, JWK or a private key)")
+	VerifyCmd.Flags().StringVarP(&verifyFlags.passphrase, "passphrase", "p", "", passphraseUsage("Passphrase when --pub is an encrypted private key"))
+	VerifyCmd.Flags().StringVar(&verifyFlags.passphraseFile, "passphrase-file", "", "Read the passphrase of an encrypted --pub key from a file")
+	VerifyCmd.Flags().StringVarP(&verifyFlags.namespace, "namespace", "n", anbuCrypto.DefaultSignNamespace, "Expected SSHSIG namespace")
+	VerifyCmd.MarkFlagRequired("pub")
+	VerifyCmd.MarkFlagsMutuallyExclusive("passphrase", "passphrase-file")
+}
@@ -0,0 +1,141 @@
+package genericsCmd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	anbuGenerics "github.com/tanq16/anbu/internal/generics"
+	u "github.com/tanq16/anbu/utils"
+)
+
+var encodingFlags struct {
+	chain string
+	guess bool
+}
+
+// encodingSteps takes the encoding from --chain or, failing that, from the
+// first argument; the remaining arguments are the input
+func encodingSteps(args []string) ([]string, []string) {
+	if encodingFlags.chain != "" {
+		steps, err := anbuGenerics.ParseEncodingChain(encodingFlags.chain)
+		if err != nil {
+			u.PrintFatal(err.Error(), err)
+		}
+		return steps, args
+	}
+	if len(args) == 0 {
+		u.PrintFatal(fmt.Sprintf("an encoding is required, use one of %s", strings.Join(anbuGenerics.EncodingNames, ", ")), nil)
+	}
+	steps, err := anbuGenerics.ParseEncodingChain(args[0])
+	if err != nil {
+		u.PrintFatal(err.Error(), err)
+	}
+	return steps, args[1:]
+}
+
+// encodingInput reads stdin byte for byte, so line endings, surrounding
+// whitespace and binary data reach the encoder unchanged
+func encodingInput(args []string) []byte {
+	if len(args) > 0 {
+		return []byte(strings.Join(args, " "))
+	}
+	input, err := u.ReadPipedBytes()
+	if err != nil {
+		u.PrintFatal("failed to read stdin", err)
+	}
+	if len(input) == 0 {
+		u.PrintFatal("no input given as argument or on stdin", nil)
+	}
+	return input
+}
+
+// stdoutTerminal tells whether notes can go to stdout, in a pipe they would
+// end up mixed into the output
+func stdoutTerminal() bool {
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// printEncodingOutput writes text and binary data (e.g., after gzip) as is,
+// or binary as hex for --for-ai where raw bytes would be unreadable. A final
+// newline is only added for text that lacks one and goes to a terminal or
+// comes from encode, so decoded output stays byte-exact in pipes
+func printEncodingOutput(data []byte, newline bool) {
+	if !anbuGenerics.IsReadableText(data) && u.GlobalForAIFlag {
+		if stdoutTerminal() {
+			u.PrintWarn("output is binary, shown as hex", nil)
+		}
+		u.PrintGeneric(hex.EncodeToString(data))
+		return
+	}
+	os.Stdout.Write(data)
+	if !anbuGenerics.IsReadableText(data) || bytes.HasSuffix(data, []byte("\n")) {
+		return
+	}
+	if newline || stdoutTerminal() {
+		u.LineBreak()
+	}
+}
+
+var EncodeCmd = &cobra.Command{
+	Use:   "encode <encoding> [text]",
+	Short: fmt.Sprintf("Encode text from arguments or stdin (%s)", strings.Join(anbuGenerics.EncodingNames, ", ")),
+	Long: `Encode text from arguments or stdin.
+
+Examples:
+  anbu encode b64 "hello world"           # base64 (b64url and b64raw for URL-safe and unpadded)
+  anbu encode url "a=1&b=two words"       # percent-encoding
+  cat payload.json | anbu encode gzb64    # gzip, then base64
+  anbu encode --chain gzip,b64,url "..."  # apply several encodings left to right`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		steps, rest := encodingSteps(args)
+		output, err := anbuGenerics.EncodeChain(steps, encodingInput(rest))
+		if err != nil {
+			u.PrintFatal(err.Error(), err)
+		}
+		printEncodingOutput(output, true)
+	},
+}
+
+var DecodeCmd = &cobra.Command{
+	Use:   "decode [encoding] [text]",
+	Short: "Decode text from arguments or stdin, or guess the encoding with --guess",
+	Long: `Decode text from arguments or stdin.
+
+Examples:
+  anbu decode b64 aGVsbG8gd29ybGQ=        # base64, padded or not
+  anbu decode --chain b64,gunzip < blob    # apply several decodings left to right
+  anbu decode --guess "H4sIAAAAAAAA..."    # detect and peel off layers of encoding`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if encodingFlags.guess {
+			output, steps := anbuGenerics.GuessDecode(encodingInput(args))
+			if len(steps) == 0 {
+				u.PrintFatal("no known encoding detected", nil)
+			}
+			if stdoutTerminal() {
+				u.PrintInfo(fmt.Sprintf("Detected --chain %s", strings.Join(steps, ",")))
+			}
+			printEncodingOutput(output, false)
+			return
+		}
+		steps, rest := encodingSteps(args)
+		output, err := anbuGenerics.DecodeChain(steps, encodingInput(rest))
+		if err != nil {
+			u.PrintFatal(err.Error(), err)
+		}
+		printEncodingOutput(output, false)
+	},
+}
+
+func init() {
+	EncodeCmd.Flags().StringVarP(&encodingFlags.chain, "chain", "c", "", "Comma-separated encodings applied in order (e.g., gzip,b64)")
+	DecodeCmd.Flags().StringVarP(&encodingFlags.chain, "chain", "c", "", "Comma-separated decodings applied in order (e.g., b64,gunzip)")
+	DecodeCmd.Flags().BoolVarP(&encodingFlags.guess, "guess", "g", false, "Detect the encoding, including nested layers")
+	DecodeCmd.MarkFlagsMutuallyExclusive("chain", "guess")
+}
@@ -1,7 +1,13 @@
 package networkCmd
 
 import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+
 	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
 	anbuNetwork "github.com/tanq16/anbu/internal/network"
 	u "github.com/tanq16/anbu/utils"
 )
@@ -10,17 +16,37 @@ var httpServerFlags struct {
 	listenAddress string
 	enableUpload  bool
 	enableTLS     bool
+	certFile      string
+	keyFile       string
+	useLocalCA    bool
 }
 
 var HTTPServerCmd = &cobra.Command{
 	Use:   "http-server",
 	Short: "Start a simple HTTP/HTTPS file server with optional file uploads",
 	Run: func(cmd *cobra.Command, args []string) {
-		server := anbuNetwork.NewHTTPServer(&anbuNetwork.HTTPServerOptions{
+		options := &anbuNetwork.HTTPServerOptions{
 			ListenAddress: httpServerFlags.listenAddress,
 			EnableUpload:  httpServerFlags.enableUpload,
 			EnableTLS:     httpServerFlags.enableTLS,
-		})
+		}
+		if httpServerFlags.certFile != "" {
+			cert, err := tls.LoadX509KeyPair(httpServerFlags.certFile, httpServerFlags.keyFile)
+			if err != nil {
+				u.PrintFatal("Failed to load TLS certificate", err)
+			}
+			options.Certificate = &cert
+			options.EnableTLS = true
+		}
+		if httpServerFlags.useLocalCA {
+			cert, err := localCACertificate(httpServerFlags.listenAddress)
+			if err != nil {
+				u.PrintFatal("Failed to issue certificate from the local CA", err)
+			}
+			options.Certificate = &cert
+			options.EnableTLS = true
+		}
+		server := anbuNetwork.NewHTTPServer(options)
 		if err := server.Setup(); err != nil {
 			u.PrintFatal("Failed to setup HTTP server", err)
 		}
@@ -31,8 +57,41 @@ var HTTPServerCmd = &cobra.Command{
 	},
 }
 
+// localCACertificate signs an in-memory certificate for every name the
+// server is likely to be reached by
+func localCACertificate(listenAddress string) (tls.Certificate, error) {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if hostname, err := os.Hostname(); err == nil {
+		hosts = append(hosts, hostname)
+	}
+	host, _, _ := net.SplitHostPort(listenAddress)
+	if ip := net.ParseIP(host); host != "" && (ip == nil || !ip.IsUnspecified()) {
+		hosts = append(hosts, host)
+	} else if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && !ipNet.IP.IsLinkLocalUnicast() {
+				hosts = append(hosts, ipNet.IP.String())
+			}
+		}
+	}
+	cert, err := anbuCrypto.IssueEphemeralCert(hosts, "")
+	if errors.Is(err, anbuCrypto.ErrPassphraseRequired) {
+		passphrase, promptErr := u.PromptPassword("CA key passphrase:")
+		if promptErr != nil {
+			return cert, promptErr
+		}
+		return anbuCrypto.IssueEphemeralCert(hosts, passphrase)
+	}
+	return cert, err
+}
+
 func init() {
 	HTTPServerCmd.Flags().StringVarP(&httpServerFlags.listenAddress, "listen", "l", "0.0.0.0:8080", "Address and port to listen on")
 	HTTPServerCmd.Flags().BoolVarP(&httpServerFlags.enableUpload, "upload", "u", false, "Enable file uploads via PUT requests")
 	HTTPServerCmd.Flags().BoolVarP(&httpServerFlags.enableTLS, "tls", "t", false, "Enable HTTPS with a self-signed certificate")
+	HTTPServerCmd.Flags().StringVar(&httpServerFlags.certFile, "cert", "", "Serve HTTPS with this certificate (e.g., from 'anbu ca issue')")
+	HTTPServerCmd.Flags().StringVar(&httpServerFlags.keyFile, "key", "", "Private key of the certificate")
+	HTTPServerCmd.Flags().BoolVar(&httpServerFlags.useLocalCA, "ca", false, "Serve HTTPS with a certificate signed by the local anbu CA")
+	HTTPServerCmd.MarkFlagsRequiredTogether("cert", "key")
+	HTTPServerCmd.MarkFlagsMutuallyExclusive("cert", "ca")
 }
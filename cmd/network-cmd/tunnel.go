@@ -2,10 +2,13 @@ package networkCmd
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"os"
 	"os/signal"
 
 	"github.com/spf13/cobra"
+	anbuCrypto "github.com/tanq16/anbu/internal/crypto"
 	anbuNetwork "github.com/tanq16/anbu/internal/network"
 	u "github.com/tanq16/anbu/utils"
 	"golang.org/x/crypto/ssh"
@@ -16,6 +19,10 @@ var tunnelFlags struct {
 	remoteAddr         string
 	useTLS             bool
 	insecureSkipVerify bool
+	trustLocalCA       bool
+	caFile             string
+	certFile           string
+	keyFile            string
 	sshAddr            string
 	sshUser            string
 	sshPassword        string
@@ -44,12 +51,48 @@ var tcpTunnelCmd = &cobra.Command{
 			ctx,
 			tunnelFlags.localAddr,
 			tunnelFlags.remoteAddr,
-			tunnelFlags.useTLS,
-			tunnelFlags.insecureSkipVerify,
+			tunnelTLSConfig(),
 		)
 	},
 }
 
+// tunnelTLSConfig returns nil for plain TCP; trusting a CA or presenting a
+// client certificate implies TLS
+func tunnelTLSConfig() *tls.Config {
+	if !tunnelFlags.useTLS && !tunnelFlags.trustLocalCA && tunnelFlags.caFile == "" && tunnelFlags.certFile == "" {
+		return nil
+	}
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: tunnelFlags.insecureSkipVerify,
+	}
+	if tunnelFlags.trustLocalCA {
+		pool, err := anbuCrypto.CACertPool()
+		if err != nil {
+			u.PrintFatal("failed to load local CA", err)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if tunnelFlags.caFile != "" {
+		data, err := os.ReadFile(tunnelFlags.caFile)
+		if err != nil {
+			u.PrintFatal("failed to read CA file", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			u.PrintFatal("no certificates found in CA file", nil)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if tunnelFlags.certFile != "" {
+		cert, err := tls.LoadX509KeyPair(tunnelFlags.certFile, tunnelFlags.keyFile)
+		if err != nil {
+			u.PrintFatal("failed to load client certificate", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig
+}
+
 var sshTunnelCmd = &cobra.Command{
 	Use:   "ssh",
 	Short: "Create an SSH forward tunnel through a jump host with password or key-based authentication",
@@ -139,6 +182,12 @@ func init() {
 	tcpTunnelCmd.Flags().StringVarP(&tunnelFlags.remoteAddr, "remote", "r", "", "Remote address to forward to")
 	tcpTunnelCmd.Flags().BoolVar(&tunnelFlags.useTLS, "tls", false, "Use TLS for the remote connection")
 	tcpTunnelCmd.Flags().BoolVar(&tunnelFlags.insecureSkipVerify, "insecure", false, "Skip TLS certificate verification")
+	tcpTunnelCmd.Flags().BoolVar(&tunnelFlags.trustLocalCA, "ca", false, "Trust certificates issued by the local anbu CA")
+	tcpTunnelCmd.Flags().StringVar(&tunnelFlags.caFile, "ca-file", "", "PEM file of CA certificates to trust instead of the system roots")
+	tcpTunnelCmd.Flags().StringVar(&tunnelFlags.certFile, "cert", "", "Client certificate for mutual TLS")
+	tcpTunnelCmd.Flags().StringVar(&tunnelFlags.keyFile, "key", "", "Private key of the client certificate")
+	tcpTunnelCmd.MarkFlagsMutuallyExclusive("ca", "ca-file")
+	tcpTunnelCmd.MarkFlagsRequiredTogether("cert", "key")
 
 	sshTunnelCmd.Flags().StringVarP(&tunnelFlags.localAddr, "local", "l", "localhost:8000", "Local address to listen on")
 	sshTunnelCmd.Flags().StringVarP(&tunnelFlags.remoteAddr, "remote", "r", "", "Remote address to forward to")
@@ -66,9 +66,18 @@ func init() {
 	rootCmd.AddCommand(genericsCmd.BulkRenameCmd)
 	rootCmd.AddCommand(genericsCmd.StashCmd)
 	rootCmd.AddCommand(genericsCmd.DuplicatesCmd)
+	rootCmd.AddCommand(genericsCmd.EncodeCmd)
+	rootCmd.AddCommand(genericsCmd.DecodeCmd)
 
 	rootCmd.AddCommand(cryptoCmd.SecretsCmd)
 	rootCmd.AddCommand(cryptoCmd.KeyPairCmd)
+	rootCmd.AddCommand(cryptoCmd.CACmd)
+	rootCmd.AddCommand(cryptoCmd.CertCmd)
+	rootCmd.AddCommand(cryptoCmd.SignCmd)
+	rootCmd.AddCommand(cryptoCmd.VerifyCmd)
+	rootCmd.AddCommand(cryptoCmd.CryptCmd)
+	rootCmd.AddCommand(cryptoCmd.HashCmd)
+	rootCmd.AddCommand(cryptoCmd.JWTCmd)
 
 	rootCmd.AddCommand(networkCmd.TunnelCmd)
 	rootCmd.AddCommand(networkCmd.HTTPServerCmd)
@@ -0,0 +1,524 @@
+package anbuCrypto
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultCAName     = "Anbu Local CA"
+	DefaultCADays     = 3650
+	DefaultCertDays   = 397
+	caCertFile        = "ca.crt"
+	caKeyFile         = "ca.key"
+	caIndexFile       = "index.json"
+	caCRLFile         = "crl.pem"
+	caCRLValidity     = 30 * 24 * time.Hour
+	ephemeralCertDays = 30
+)
+
+type CAOptions struct {
+	Name       string
+	KeyType    string
+	Days       int
+	Passphrase string
+	Force      bool
+}
+
+type CertOptions struct {
+	Name       string
+	DNSNames   []string
+	IPs        []string
+	KeyType    string
+	Days       int
+	Client     bool
+	OutputDir  string
+	Passphrase string
+}
+
+type CAInfo struct {
+	Subject     string
+	Fingerprint string
+	NotAfter    time.Time
+	CertPath    string
+	KeyPath     string
+	CRLPath     string
+}
+
+type CAIssuedCert struct {
+	Serial      string     `json:"serial"`
+	Name        string     `json:"name"`
+	DNSNames    []string   `json:"dns_names,omitempty"`
+	IPAddresses []string   `json:"ip_addresses,omitempty"`
+	IssuedAt    time.Time  `json:"issued_at"`
+	NotAfter    time.Time  `json:"not_after"`
+	CertPath    string     `json:"cert_path"`
+	KeyPath     string     `json:"key_path"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	Reason      int        `json:"reason,omitempty"`
+}
+
+type caIndex struct {
+	CRLNumber int64           `json:"crl_number"`
+	Certs     []*CAIssuedCert `json:"certs"`
+}
+
+// RFC 5280 CRLReason codes accepted by `anbu ca revoke --reason`
+var CRLReasons = map[string]int{
+	"unspecified":            0,
+	"key-compromise":         1,
+	"ca-compromise":          2,
+	"affiliation-changed":    3,
+	"superseded":             4,
+	"cessation-of-operation": 5,
+	"certificate-hold":       6,
+	"privilege-withdrawn":    9,
+	"aa-compromise":          10,
+}
+
+func CADir() (string, error) {
+	anbuDir, err := secretsConfigDir()
+	if err != nil {
+		return "", err
+	}
+	caDir := filepath.Join(anbuDir, "ca")
+	if err := os.MkdirAll(caDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create CA directory: %w", err)
+	}
+	return caDir, nil
+}
+
+func CACertPath() (string, error) {
+	caDir, err := CADir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(caDir, caCertFile), nil
+}
+
+func certFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return colonHex(sum[:])
+}
+
+func randomSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	return serial, nil
+}
+
+func InitCA(opts CAOptions) (*CAInfo, error) {
+	caDir, err := CADir()
+	if err != nil {
+		return nil, err
+	}
+	certPath := filepath.Join(caDir, caCertFile)
+	keyPath := filepath.Join(caDir, caKeyFile)
+	if _, err := os.Stat(certPath); err == nil && !opts.Force {
+		return nil, fmt.Errorf("a CA already exists at %s", caDir)
+	}
+	if opts.Name == "" {
+		opts.Name = DefaultCAName
+	}
+	if opts.Days <= 0 {
+		opts.Days = DefaultCADays
+	}
+	key, _, err := generatePrivateKey(KeyPairOptions{Type: opts.KeyType, KeySize: 3072})
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: opts.Name, Organization: []string{"Anbu"}},
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.AddDate(0, 0, opts.Days),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	keyBlock, err := marshalPEMPrivateKey(key, true, opts.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(keyPath, pem.EncodeToMemory(keyBlock), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write CA key: %w", err)
+	}
+	if err := writeFileAtomic(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write CA certificate: %w", err)
+	}
+	index := &caIndex{}
+	if err := writeCRL(caDir, cert, key, index); err != nil {
+		return nil, err
+	}
+	return &CAInfo{
+		Subject:     cert.Subject.String(),
+		Fingerprint: certFingerprint(cert),
+		NotAfter:    cert.NotAfter,
+		CertPath:    certPath,
+		KeyPath:     keyPath,
+		CRLPath:     filepath.Join(caDir, caCRLFile),
+	}, nil
+}
+
+func readCertificateFile(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no certificate found in %s", path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func loadCA(passphrase string) (string, *x509.Certificate, crypto.Signer, error) {
+	caDir, err := CADir()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	cert, err := readCertificateFile(filepath.Join(caDir, caCertFile))
+	if os.IsNotExist(err) {
+		return "", nil, nil, fmt.Errorf("no CA found, create one with 'anbu ca init'")
+	}
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	keyFile, err := LoadPrivateKeyFile(filepath.Join(caDir, caKeyFile), passphrase)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return caDir, cert, keyFile.Key, nil
+}
+
+func loadCAIndex(caDir string) (*caIndex, error) {
+	index := &caIndex{}
+	data, err := os.ReadFile(filepath.Join(caDir, caIndexFile))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA index: %w", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse CA index: %w", err)
+	}
+	return index, nil
+}
+
+func saveCAIndex(caDir string, index *caIndex) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(caDir, caIndexFile), data, 0600)
+}
+
+func writeCRL(caDir string, caCert *x509.Certificate, caKey crypto.Signer, index *caIndex) error {
+	index.CRLNumber++
+	var revoked []x509.RevocationListEntry
+	for _, issued := range index.Certs {
+		if issued.RevokedAt == nil {
+			continue
+		}
+		serial, ok := new(big.Int).SetString(issued.Serial, 16)
+		if !ok {
+			return fmt.Errorf("invalid serial '%s' in CA index", issued.Serial)
+		}
+		revoked = append(revoked, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: *issued.RevokedAt,
+			ReasonCode:     issued.Reason,
+		})
+	}
+	now := time.Now()
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(index.CRLNumber),
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(caCRLValidity),
+		RevokedCertificateEntries: revoked,
+	}, caCert, caKey)
+	if err != nil {
+		return fmt.Errorf("failed to create CRL: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(caDir, caCRLFile), pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), 0644); err != nil {
+		return fmt.Errorf("failed to write CRL: %w", err)
+	}
+	return saveCAIndex(caDir, index)
+}
+
+func newLeafCertificate(caCert *x509.Certificate, caKey crypto.Signer, opts CertOptions) (*x509.Certificate, crypto.Signer, error) {
+	if opts.KeyType == "" {
+		opts.KeyType = KeyTypeECDSAP256
+	}
+	if opts.Days <= 0 {
+		opts.Days = DefaultCertDays
+	}
+	dnsNames := opts.DNSNames
+	var ips []net.IP
+	for _, value := range opts.IPs {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, nil, fmt.Errorf("invalid IP address '%s'", value)
+		}
+		ips = append(ips, ip)
+	}
+	if len(dnsNames) == 0 && len(ips) == 0 {
+		if ip := net.ParseIP(opts.Name); ip != nil {
+			ips = append(ips, ip)
+		} else {
+			dnsNames = []string{opts.Name}
+		}
+	}
+	key, _, err := generatePrivateKey(KeyPairOptions{Type: opts.KeyType, KeySize: 2048})
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	notAfter := now.AddDate(0, 0, opts.Days)
+	if notAfter.After(caCert.NotAfter) {
+		notAfter = caCert.NotAfter
+	}
+	keyUsage := x509.KeyUsageDigitalSignature
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	extKeyUsage := []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	if opts.Client {
+		extKeyUsage = append(extKeyUsage, x509.ExtKeyUsageClientAuth)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: opts.Name},
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              notAfter,
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           extKeyUsage,
+		BasicConstraintsValid: true,
+		DNSNames:              dnsNames,
+		IPAddresses:           ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+func IssueCert(opts CertOptions) (*CAIssuedCert, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("a certificate name is required")
+	}
+	if strings.ContainsAny(opts.Name, `/\`) {
+		return nil, fmt.Errorf("invalid certificate name '%s'", opts.Name)
+	}
+	caDir, caCert, caKey, err := loadCA(opts.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	outputDir := opts.OutputDir
+	if outputDir == "" {
+		outputDir = filepath.Join(caDir, "issued")
+	}
+	certPath := filepath.Join(outputDir, opts.Name+".crt")
+	keyPath := filepath.Join(outputDir, opts.Name+".key")
+	// the index keeps pointing at these paths, so earlier certificates must
+	// not be replaced underneath it
+	for _, path := range []string{certPath, keyPath} {
+		if _, err := os.Stat(path); err == nil {
+			return nil, fmt.Errorf("%s already exists, use another name or output directory", path)
+		}
+	}
+	cert, key, err := newLeafCertificate(caCert, caKey, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(outputDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	keyBlock, err := marshalPEMPrivateKey(key, true, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(keyPath, pem.EncodeToMemory(keyBlock), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key: %w", err)
+	}
+	if err := writeFileAtomic(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write certificate: %w", err)
+	}
+
+	issued := &CAIssuedCert{
+		Serial:   cert.SerialNumber.Text(16),
+		Name:     opts.Name,
+		DNSNames: cert.DNSNames,
+		IssuedAt: time.Now().UTC(),
+		NotAfter: cert.NotAfter.UTC(),
+		CertPath: certPath,
+		KeyPath:  keyPath,
+	}
+	for _, ip := range cert.IPAddresses {
+		issued.IPAddresses = append(issued.IPAddresses, ip.String())
+	}
+	index, err := loadCAIndex(caDir)
+	if err != nil {
+		return nil, err
+	}
+	index.Certs = append(index.Certs, issued)
+	if err := saveCAIndex(caDir, index); err != nil {
+		return nil, fmt.Errorf("failed to save CA index: %w", err)
+	}
+	return issued, nil
+}
+
+// IssueEphemeralCert signs a short-lived in-memory server certificate for the
+// given hosts; it is not written to disk or recorded in the CA index
+func IssueEphemeralCert(hosts []string, passphrase string) (tls.Certificate, error) {
+	_, caCert, caKey, err := loadCA(passphrase)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	opts := CertOptions{Name: "anbu", Days: ephemeralCertDays}
+	for _, host := range hosts {
+		if net.ParseIP(host) != nil {
+			opts.IPs = append(opts.IPs, host)
+		} else if host != "" {
+			opts.DNSNames = append(opts.DNSNames, host)
+		}
+	}
+	if len(opts.DNSNames) > 0 {
+		opts.Name = opts.DNSNames[0]
+	}
+	cert, key, err := newLeafCertificate(caCert, caKey, opts)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{cert.Raw, caCert.Raw}, PrivateKey: key, Leaf: cert}, nil
+}
+
+func ListIssuedCerts() ([]*CAIssuedCert, error) {
+	caDir, err := CADir()
+	if err != nil {
+		return nil, err
+	}
+	index, err := loadCAIndex(caDir)
+	if err != nil {
+		return nil, err
+	}
+	return index.Certs, nil
+}
+
+// RevokeCert accepts a hex serial or the name the certificate was issued
+// under; names must be unambiguous among unrevoked certificates
+func RevokeCert(serialOrName string, reason int, passphrase string) (*CAIssuedCert, error) {
+	caDir, caCert, caKey, err := loadCA(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	index, err := loadCAIndex(caDir)
+	if err != nil {
+		return nil, err
+	}
+	serial := strings.ToLower(strings.ReplaceAll(serialOrName, ":", ""))
+	var matches []*CAIssuedCert
+	for _, issued := range index.Certs {
+		if strings.TrimLeft(issued.Serial, "0") == strings.TrimLeft(serial, "0") {
+			matches = []*CAIssuedCert{issued}
+			break
+		}
+		if issued.Name == serialOrName && issued.RevokedAt == nil {
+			matches = append(matches, issued)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no certificate matches '%s'", serialOrName)
+	}
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("%d certificates are named '%s', revoke by serial", len(matches), serialOrName)
+	}
+	target := matches[0]
+	if target.RevokedAt != nil {
+		return nil, fmt.Errorf("certificate %s is already revoked", target.Serial)
+	}
+	now := time.Now().UTC()
+	target.RevokedAt = &now
+	target.Reason = reason
+	if err := writeCRL(caDir, caCert, caKey, index); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// CACertPool trusts the local CA on top of the system roots
+func CACertPool() (*x509.CertPool, error) {
+	certPath, err := CACertPath()
+	if err != nil {
+		return nil, err
+	}
+	cert, err := readCertificateFile(certPath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no CA found, create one with 'anbu ca init'")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	pool.AddCert(cert)
+	return pool, nil
+}
+
+// RefreshCRL re-signs the CRL so its next-update time moves forward
+func RefreshCRL(passphrase string) (string, error) {
+	caDir, caCert, caKey, err := loadCA(passphrase)
+	if err != nil {
+		return "", err
+	}
+	index, err := loadCAIndex(caDir)
+	if err != nil {
+		return "", err
+	}
+	if err := writeCRL(caDir, caCert, caKey, index); err != nil {
+		return "", err
+	}
+	return filepath.Join(caDir, caCRLFile), nil
+}
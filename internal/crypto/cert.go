@@ -0,0 +1,349 @@
+package anbuCrypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+type CSROptions struct {
+	CommonName    string
+	Organization  string
+	DNSNames      []string
+	IPs           []string
+	Emails        []string
+	KeyPath       string
+	KeyPassphrase string
+	KeyType       string
+	OutputPath    string
+	// Force allows replacing an existing key when a new one is generated
+	Force bool
+}
+
+type CSRResult struct {
+	CSRPath     string
+	KeyPath     string
+	KeyType     string
+	Fingerprint string
+}
+
+// CreateCSR signs a PKCS#10 request with an existing key, or generates one
+// next to the request when no key is given
+func CreateCSR(opts CSROptions) (*CSRResult, error) {
+	if opts.CommonName == "" && len(opts.DNSNames) == 0 {
+		return nil, fmt.Errorf("a common name or DNS name is required")
+	}
+	if opts.CommonName == "" {
+		opts.CommonName = opts.DNSNames[0]
+	}
+	outputPath := opts.OutputPath
+	if outputPath == "" {
+		outputPath = opts.CommonName + ".csr"
+	}
+	template := &x509.CertificateRequest{
+		Subject:        pkix.Name{CommonName: opts.CommonName},
+		DNSNames:       opts.DNSNames,
+		EmailAddresses: opts.Emails,
+	}
+	if opts.Organization != "" {
+		template.Subject.Organization = []string{opts.Organization}
+	}
+	for _, value := range opts.IPs {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address '%s'", value)
+		}
+		template.IPAddresses = append(template.IPAddresses, ip)
+	}
+
+	result := &CSRResult{CSRPath: outputPath}
+	var key any
+	var newKey *pem.Block
+	if opts.KeyPath != "" {
+		keyFile, err := LoadPrivateKeyFile(opts.KeyPath, opts.KeyPassphrase)
+		if err != nil {
+			return nil, err
+		}
+		key = keyFile.Key
+	} else {
+		// the existing key may already back an issued certificate
+		result.KeyPath = strings.TrimSuffix(outputPath, ".csr") + ".key"
+		if _, err := os.Stat(result.KeyPath); err == nil && !opts.Force {
+			return nil, fmt.Errorf("%s already exists, pass it with --key or use --force to replace it", result.KeyPath)
+		}
+		if opts.KeyType == "" {
+			opts.KeyType = KeyTypeECDSAP256
+		}
+		signer, _, err := generatePrivateKey(KeyPairOptions{Type: opts.KeyType, KeySize: 2048})
+		if err != nil {
+			return nil, err
+		}
+		if newKey, err = marshalPEMPrivateKey(signer, true, ""); err != nil {
+			return nil, err
+		}
+		key = signer
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CSR: %w", err)
+	}
+	if newKey != nil {
+		if err := writeFileAtomic(result.KeyPath, pem.EncodeToMemory(newKey), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write key: %w", err)
+		}
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, err
+	}
+	if result.KeyType, _, err = publicKeyAlgorithm(csr.PublicKey); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(outputPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write CSR: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	result.Fingerprint = colonHex(sum[:])
+	return result, nil
+}
+
+// ParseCertificateData reads PEM (any number of CERTIFICATE and CERTIFICATE
+// REQUEST blocks) or DER (a single certificate, a concatenated chain or a CSR)
+func ParseCertificateData(data []byte) ([]*x509.Certificate, []*x509.CertificateRequest, error) {
+	var certs []*x509.Certificate
+	var csrs []*x509.CertificateRequest
+	rest := data
+	found := false
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		found = true
+		switch block.Type {
+		case "CERTIFICATE", "TRUSTED CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to parse certificate %d: %w", len(certs)+1, err)
+			}
+			certs = append(certs, cert)
+		case "CERTIFICATE REQUEST", "NEW CERTIFICATE REQUEST":
+			csr, err := x509.ParseCertificateRequest(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to parse CSR: %w", err)
+			}
+			csrs = append(csrs, csr)
+		}
+	}
+	if found {
+		if len(certs) == 0 && len(csrs) == 0 {
+			return nil, nil, fmt.Errorf("no certificates or CSRs found in PEM data")
+		}
+		return certs, csrs, nil
+	}
+	// DER is parsed untouched, trailing signature bytes may look like whitespace
+	if parsed, err := x509.ParseCertificates(data); err == nil && len(parsed) > 0 {
+		return parsed, nil, nil
+	}
+	if csr, err := x509.ParseCertificateRequest(data); err == nil {
+		return nil, []*x509.CertificateRequest{csr}, nil
+	}
+	return nil, nil, fmt.Errorf("input is neither a PEM nor a DER certificate or CSR")
+}
+
+func colonHex(data []byte) string {
+	encoded := strings.ToUpper(hex.EncodeToString(data))
+	var parts []string
+	for i := 0; i < len(encoded); i += 2 {
+		parts = append(parts, encoded[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
+
+var keyUsageNames = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "Digital Signature"},
+	{x509.KeyUsageContentCommitment, "Content Commitment"},
+	{x509.KeyUsageKeyEncipherment, "Key Encipherment"},
+	{x509.KeyUsageDataEncipherment, "Data Encipherment"},
+	{x509.KeyUsageKeyAgreement, "Key Agreement"},
+	{x509.KeyUsageCertSign, "Certificate Sign"},
+	{x509.KeyUsageCRLSign, "CRL Sign"},
+	{x509.KeyUsageEncipherOnly, "Encipher Only"},
+	{x509.KeyUsageDecipherOnly, "Decipher Only"},
+}
+
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "Any",
+	x509.ExtKeyUsageServerAuth:      "Server Auth",
+	x509.ExtKeyUsageClientAuth:      "Client Auth",
+	x509.ExtKeyUsageCodeSigning:     "Code Signing",
+	x509.ExtKeyUsageEmailProtection: "Email Protection",
+	x509.ExtKeyUsageTimeStamping:    "Time Stamping",
+	x509.ExtKeyUsageOCSPSigning:     "OCSP Signing",
+}
+
+func subjectAltNames(dnsNames []string, ips []net.IP, emails []string, uris []string) string {
+	var names []string
+	for _, name := range dnsNames {
+		names = append(names, "DNS:"+name)
+	}
+	for _, ip := range ips {
+		names = append(names, "IP:"+ip.String())
+	}
+	for _, email := range emails {
+		names = append(names, "email:"+email)
+	}
+	for _, uri := range uris {
+		names = append(names, "URI:"+uri)
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ", ")
+}
+
+func publicKeyDescription(publicKey any) string {
+	keyType, bits, err := publicKeyAlgorithm(publicKey)
+	if err != nil {
+		return fmt.Sprintf("%T", publicKey)
+	}
+	return fmt.Sprintf("%s (%d bits)", keyType, bits)
+}
+
+func validityStatus(cert *x509.Certificate, now time.Time) string {
+	switch {
+	case now.Before(cert.NotBefore):
+		return "not yet valid"
+	case now.After(cert.NotAfter):
+		return fmt.Sprintf("expired %d days ago", int(now.Sub(cert.NotAfter).Hours()/24))
+	default:
+		return fmt.Sprintf("valid, %d days left", int(cert.NotAfter.Sub(now).Hours()/24))
+	}
+}
+
+// CertificateFields returns the property/value rows `anbu cert show` prints
+func CertificateFields(cert *x509.Certificate) [][]string {
+	var usages []string
+	for _, ku := range keyUsageNames {
+		if cert.KeyUsage&ku.usage != 0 {
+			usages = append(usages, ku.name)
+		}
+	}
+	var extUsages []string
+	for _, eku := range cert.ExtKeyUsage {
+		if name, ok := extKeyUsageNames[eku]; ok {
+			extUsages = append(extUsages, name)
+		} else {
+			extUsages = append(extUsages, fmt.Sprintf("%d", eku))
+		}
+	}
+	for _, oid := range cert.UnknownExtKeyUsage {
+		extUsages = append(extUsages, oid.String())
+	}
+	var uris []string
+	for _, uri := range cert.URIs {
+		uris = append(uris, uri.String())
+	}
+	basic := "-"
+	if cert.BasicConstraintsValid {
+		basic = "CA: false"
+		if cert.IsCA {
+			basic = "CA: true"
+			if cert.MaxPathLen > 0 || cert.MaxPathLenZero {
+				basic += fmt.Sprintf(", path length %d", cert.MaxPathLen)
+			}
+		}
+	}
+	sha256Sum := sha256.Sum256(cert.Raw)
+	sha1Sum := sha1.Sum(cert.Raw)
+	rows := [][]string{
+		{"Subject", cert.Subject.String()},
+		{"Issuer", cert.Issuer.String()},
+		{"Serial", colonHex(cert.SerialNumber.Bytes())},
+		{"Not Before", cert.NotBefore.UTC().Format(time.RFC3339)},
+		{"Not After", cert.NotAfter.UTC().Format(time.RFC3339)},
+		{"Status", validityStatus(cert, time.Now())},
+		{"SANs", subjectAltNames(cert.DNSNames, cert.IPAddresses, cert.EmailAddresses, uris)},
+		{"Public Key", publicKeyDescription(cert.PublicKey)},
+		{"Signature", cert.SignatureAlgorithm.String()},
+		{"Key Usage", joinOrDash(usages)},
+		{"Ext Key Usage", joinOrDash(extUsages)},
+		{"Basic Constraints", basic},
+		{"SHA256", colonHex(sha256Sum[:])},
+		{"SHA1", colonHex(sha1Sum[:])},
+	}
+	if len(cert.CRLDistributionPoints) > 0 {
+		rows = append(rows, []string{"CRL", strings.Join(cert.CRLDistributionPoints, ", ")})
+	}
+	return rows
+}
+
+func CSRFields(csr *x509.CertificateRequest) [][]string {
+	var uris []string
+	for _, uri := range csr.URIs {
+		uris = append(uris, uri.String())
+	}
+	signature := "valid"
+	if err := csr.CheckSignature(); err != nil {
+		signature = "INVALID: " + err.Error()
+	}
+	sha256Sum := sha256.Sum256(csr.Raw)
+	return [][]string{
+		{"Subject", csr.Subject.String()},
+		{"SANs", subjectAltNames(csr.DNSNames, csr.IPAddresses, csr.EmailAddresses, uris)},
+		{"Public Key", publicKeyDescription(csr.PublicKey)},
+		{"Signature", fmt.Sprintf("%s (%s)", csr.SignatureAlgorithm, signature)},
+		{"SHA256", colonHex(sha256Sum[:])},
+	}
+}
+
+func joinOrDash(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ", ")
+}
+
+// CheckChainOrder reports which certificate in the list issued each one;
+// a correctly ordered chain has every certificate issued by the next
+func CheckChainOrder(certs []*x509.Certificate) []string {
+	var statuses []string
+	for i, cert := range certs {
+		if bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil {
+			statuses = append(statuses, "self-signed root")
+			continue
+		}
+		issuer := -1
+		for j, candidate := range certs {
+			if j != i && cert.CheckSignatureFrom(candidate) == nil {
+				issuer = j
+				if j == i+1 {
+					break
+				}
+			}
+		}
+		switch {
+		case issuer == i+1:
+			statuses = append(statuses, fmt.Sprintf("issued by #%d", issuer+1))
+		case issuer >= 0:
+			statuses = append(statuses, fmt.Sprintf("issued by #%d (out of order)", issuer+1))
+		default:
+			statuses = append(statuses, "issuer not in chain")
+		}
+	}
+	return statuses
+}
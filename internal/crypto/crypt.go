@@ -0,0 +1,359 @@
+package anbuCrypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	"golang.org/x/crypto/ssh"
+)
+
+// CryptOptions configures `anbu crypt`. Files are written in the age v1
+// format, so they can be exchanged with the age and rage tools
+type CryptOptions struct {
+	Recipients     []string
+	RecipientFiles []string
+	Identities     []string
+	Passphrase     string
+	Armor          bool
+	// PromptPassphrase is called when an encrypted identity or a passphrase
+	// protected file needs a passphrase that was not given up front
+	PromptPassphrase func(label string) (string, error)
+}
+
+func (opts CryptOptions) passphraseFor(label string) (string, error) {
+	if opts.PromptPassphrase == nil {
+		return "", ErrPassphraseRequired
+	}
+	return opts.PromptPassphrase(label)
+}
+
+func parseAgeRecipient(value string) (age.Recipient, error) {
+	if strings.HasPrefix(value, "ssh-") {
+		return agessh.ParseRecipient(value)
+	}
+	recipients, err := age.ParseRecipients(strings.NewReader(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient '%s'", value)
+	}
+	return recipients[0], nil
+}
+
+func sshAgeRecipient(publicKey crypto.PublicKey) (age.Recipient, error) {
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH public key: %w", err)
+	}
+	switch sshPublicKey.Type() {
+	case ssh.KeyAlgoED25519:
+		return agessh.NewEd25519Recipient(sshPublicKey)
+	case ssh.KeyAlgoRSA:
+		return agessh.NewRSARecipient(sshPublicKey)
+	default:
+		return nil, fmt.Errorf("age only supports ssh-ed25519 and ssh-rsa keys, not %s", sshPublicKey.Type())
+	}
+}
+
+// parseAgeRecipientFile reads age recipients files (one recipient per line)
+// as well as any single public key 'anbu kp' writes
+func parseAgeRecipientFile(path string) ([]age.Recipient, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipients file: %w", err)
+	}
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("-----BEGIN")) || bytes.HasPrefix(trimmed, []byte("{")) {
+		key, err := loadKeyMaterial(path, "")
+		if err != nil {
+			return nil, err
+		}
+		recipient, err := sshAgeRecipient(key.public)
+		if err != nil {
+			return nil, err
+		}
+		return []age.Recipient{recipient}, nil
+	}
+	var recipients []age.Recipient
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		recipient, err := parseAgeRecipient(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no recipients found in %s", path)
+	}
+	return recipients, nil
+}
+
+func sshAgeIdentity(key crypto.Signer) (age.Identity, error) {
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		return agessh.NewEd25519Identity(k)
+	case *rsa.PrivateKey:
+		return agessh.NewRSAIdentity(k)
+	default:
+		return nil, fmt.Errorf("age only supports Ed25519 and RSA identities, not %T", key)
+	}
+}
+
+// loadAgeIdentities accepts age identity files and Ed25519 or RSA private
+// keys in any format 'anbu kp' writes. Without a passphrase, encrypted
+// OpenSSH keys only ask for one when the file was actually encrypted to them
+func loadAgeIdentities(path string, opts CryptOptions) ([]age.Identity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity file: %w", err)
+	}
+	if bytes.Contains(data, []byte("AGE-SECRET-KEY-")) {
+		identities, err := age.ParseIdentities(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return identities, nil
+	}
+	label := fmt.Sprintf("Passphrase for %s", path)
+	key, err := loadKeyMaterial(path, opts.Passphrase)
+	if errors.Is(err, ErrPassphraseRequired) {
+		passphrase, promptErr := opts.passphraseFor(label)
+		if promptErr != nil {
+			return nil, promptErr
+		}
+		key, err = loadKeyMaterial(path, passphrase)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.private != nil {
+		identity, err := sshAgeIdentity(key.private)
+		if err != nil {
+			return nil, err
+		}
+		return []age.Identity{identity}, nil
+	}
+	if !key.encrypted {
+		return nil, fmt.Errorf("%s is a public key, decryption needs the private key", path)
+	}
+	sshPublicKey, err := ssh.NewPublicKey(key.public)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH public key: %w", err)
+	}
+	identity, err := agessh.NewEncryptedSSHIdentity(sshPublicKey, data, func() ([]byte, error) {
+		passphrase, err := opts.passphraseFor(label)
+		return []byte(passphrase), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []age.Identity{identity}, nil
+}
+
+// scryptPromptIdentity defers asking for the passphrase until the file turns
+// out to be passphrase protected
+type scryptPromptIdentity struct {
+	opts CryptOptions
+}
+
+func (i *scryptPromptIdentity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
+	if !slices.ContainsFunc(stanzas, func(s *age.Stanza) bool { return s.Type == "scrypt" }) {
+		return nil, age.ErrIncorrectIdentity
+	}
+	passphrase := i.opts.Passphrase
+	if passphrase == "" {
+		var err error
+		if passphrase, err = i.opts.passphraseFor("Passphrase"); err != nil {
+			return nil, err
+		}
+	}
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := identity.Unwrap(stanzas)
+	if errors.Is(err, age.ErrIncorrectIdentity) {
+		return nil, fmt.Errorf("incorrect passphrase")
+	}
+	return fileKey, err
+}
+
+func (opts CryptOptions) recipients() ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, value := range opts.Recipients {
+		recipient, err := parseAgeRecipient(value)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+	for _, path := range opts.RecipientFiles {
+		parsed, err := parseAgeRecipientFile(path)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, parsed...)
+	}
+	if opts.Passphrase != "" {
+		if len(recipients) > 0 {
+			return nil, fmt.Errorf("a passphrase cannot be combined with recipients")
+		}
+		recipient, err := age.NewScryptRecipient(opts.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("a passphrase or at least one recipient is required")
+	}
+	return recipients, nil
+}
+
+// EncryptStream encrypts src to dst in 64 KiB authenticated chunks, so
+// inputs of any size stream through in constant memory
+func EncryptStream(dst io.Writer, src io.Reader, opts CryptOptions) error {
+	recipients, err := opts.recipients()
+	if err != nil {
+		return err
+	}
+	out := dst
+	var armorWriter io.WriteCloser
+	if opts.Armor {
+		armorWriter = armor.NewWriter(dst)
+		out = armorWriter
+	}
+	writer, err := age.Encrypt(out, recipients...)
+	if err != nil {
+		return fmt.Errorf("failed to start encryption: %w", err)
+	}
+	if _, err := io.Copy(writer, src); err != nil {
+		return fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to finish encryption: %w", err)
+	}
+	if armorWriter != nil {
+		return armorWriter.Close()
+	}
+	return nil
+}
+
+// DecryptStream decrypts binary or armored age input. Chunks are verified
+// before they are written, but a truncated or tampered file fails midway
+func DecryptStream(dst io.Writer, src io.Reader, opts CryptOptions) error {
+	var identities []age.Identity
+	for _, path := range opts.Identities {
+		parsed, err := loadAgeIdentities(path, opts)
+		if err != nil {
+			return err
+		}
+		identities = append(identities, parsed...)
+	}
+	identities = append(identities, &scryptPromptIdentity{opts: opts})
+	buffered := bufio.NewReader(src)
+	var in io.Reader = buffered
+	if header, _ := buffered.Peek(len(armor.Header)); string(header) == armor.Header {
+		in = armor.NewReader(buffered)
+	}
+	reader, err := age.Decrypt(in, identities...)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return fmt.Errorf("none of the given identities can decrypt this file")
+		}
+		return err
+	}
+	if _, err := io.Copy(dst, reader); err != nil {
+		return fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return nil
+}
+
+func openCryptInput(inputPath string) (io.ReadCloser, error) {
+	if inputPath == "" || inputPath == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input: %w", err)
+	}
+	return file, nil
+}
+
+func cryptFile(inputPath, outputPath string, perm os.FileMode, process func(io.Writer, io.Reader) error) error {
+	input, err := openCryptInput(inputPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	if outputPath == "" || outputPath == "-" {
+		return process(os.Stdout, input)
+	}
+	return writeStreamAtomic(outputPath, perm, func(w io.Writer) error {
+		return process(w, input)
+	})
+}
+
+// CryptOutputPath picks <file>.age for encryption and strips .age for
+// decryption; stdin input or a decrypted file without .age goes to stdout
+func CryptOutputPath(inputPath string, decrypt bool) string {
+	if inputPath == "" || inputPath == "-" {
+		return "-"
+	}
+	if !decrypt {
+		return inputPath + ".age"
+	}
+	if trimmed, ok := strings.CutSuffix(inputPath, ".age"); ok && trimmed != "" {
+		return trimmed
+	}
+	return "-"
+}
+
+func EncryptFile(inputPath, outputPath string, opts CryptOptions) error {
+	return cryptFile(inputPath, outputPath, 0644, func(w io.Writer, r io.Reader) error {
+		return EncryptStream(w, r, opts)
+	})
+}
+
+func DecryptFile(inputPath, outputPath string, opts CryptOptions) error {
+	return cryptFile(inputPath, outputPath, 0600, func(w io.Writer, r io.Reader) error {
+		return DecryptStream(w, r, opts)
+	})
+}
+
+// GenerateAgeIdentity writes a new X25519 identity in the age-keygen layout
+// and returns its age1 recipient
+func GenerateAgeIdentity(outputPath string) (string, error) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate identity: %w", err)
+	}
+	recipient := identity.Recipient().String()
+	data := fmt.Sprintf("# created: %s\n# public key: %s\n%s\n", time.Now().Format(time.RFC3339), recipient, identity.String())
+	if outputPath == "" || outputPath == "-" {
+		_, err = os.Stdout.WriteString(data)
+		return recipient, err
+	}
+	if _, err := os.Stat(outputPath); err == nil {
+		return "", fmt.Errorf("%s already exists", outputPath)
+	}
+	if err := writeFileAtomic(outputPath, []byte(data), 0600); err != nil {
+		return "", fmt.Errorf("failed to write identity: %w", err)
+	}
+	return recipient, nil
+}
@@ -0,0 +1,355 @@
+package anbuCrypto
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+
+	u "github.com/tanq16/anbu/utils"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/sync/errgroup"
+	"lukechampine.com/blake3"
+)
+
+const (
+	HashMD5     = "md5"
+	HashSHA1    = "sha1"
+	HashSHA256  = "sha256"
+	HashSHA512  = "sha512"
+	HashBLAKE2b = "blake2b"
+	HashBLAKE3  = "blake3"
+
+	HashStatusOK       = "OK"
+	HashStatusMismatch = "MISMATCH"
+	HashStatusMissing  = "MISSING"
+	HashStatusExtra    = "EXTRA"
+	HashStatusError    = "ERROR"
+)
+
+var HashAlgorithms = []string{HashMD5, HashSHA1, HashSHA256, HashSHA512, HashBLAKE2b, HashBLAKE3}
+
+// BSD-style tags as written by `shasum --tag` and `b2sum --tag`
+var hashTags = map[string]string{
+	"MD5":     HashMD5,
+	"SHA1":    HashSHA1,
+	"SHA256":  HashSHA256,
+	"SHA512":  HashSHA512,
+	"BLAKE2b": HashBLAKE2b,
+	"BLAKE3":  HashBLAKE3,
+}
+
+var (
+	gnuManifestLine = regexp.MustCompile(`^\\?([0-9a-fA-F]+) [ *](.+)$`)
+	bsdManifestLine = regexp.MustCompile(`^(\w+) \((.+)\) = ([0-9a-fA-F]+)$`)
+)
+
+type FileHash struct {
+	Path string
+	Hash string
+	Err  error
+}
+
+type ManifestEntry struct {
+	Path string
+	Hash string
+}
+
+type HashVerifyResult struct {
+	Path     string
+	Status   string
+	Expected string
+	Actual   string
+}
+
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case HashMD5:
+		return md5.New(), nil
+	case HashSHA1:
+		return sha1.New(), nil
+	case HashSHA256:
+		return sha256.New(), nil
+	case HashSHA512:
+		return sha512.New(), nil
+	case HashBLAKE2b:
+		return blake2b.New512(nil)
+	case HashBLAKE3:
+		return blake3.New(32, nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm '%s', use one of %s", algorithm, strings.Join(HashAlgorithms, ", "))
+	}
+}
+
+func HashReader(r io.Reader, algorithm string) (string, error) {
+	h, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// expandHashPaths turns directories into the sorted list of regular files below them
+func expandHashPaths(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(file string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk directory: %w", err)
+		}
+	}
+	return files, nil
+}
+
+func hashFilesParallel(files []string, algorithm string, workers int) ([]FileHash, error) {
+	if _, err := newHash(algorithm); err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	results := make([]FileHash, len(files))
+	var g errgroup.Group
+	g.SetLimit(workers)
+	for i, file := range files {
+		g.Go(func() error {
+			h, _ := newHash(algorithm)
+			sum, err := u.ComputeFileHashWith(file, h)
+			results[i] = FileHash{Path: file, Hash: sum, Err: err}
+			return nil
+		})
+	}
+	g.Wait()
+	return results, nil
+}
+
+// HashFiles hashes files and directory trees with a pool of workers, keeping
+// the order of the input paths
+func HashFiles(paths []string, algorithm string, workers int) ([]FileHash, error) {
+	files, err := expandHashPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+	return hashFilesParallel(files, algorithm, workers)
+}
+
+// WriteHashManifest writes sha256sum-compatible lines, or BSD-tagged lines
+// (`BLAKE3 (path) = hash`) for other algorithms since their digest length
+// alone does not tell them apart. Paths are stored relative to the manifest
+// so the tree can be verified after moving it
+func WriteHashManifest(manifestPath, algorithm string, hashes []FileHash) error {
+	tag := ""
+	if algorithm != HashSHA256 {
+		for name, tagged := range hashTags {
+			if tagged == algorithm {
+				tag = name
+			}
+		}
+		if tag == "" {
+			return fmt.Errorf("unsupported hash algorithm '%s'", algorithm)
+		}
+	}
+	var builder strings.Builder
+	baseDir, manifestAbs := "", ""
+	if manifestPath != "-" {
+		manifestAbs, _ = filepath.Abs(manifestPath)
+		baseDir = filepath.Dir(manifestAbs)
+	}
+	for _, fileHash := range hashes {
+		if fileHash.Err != nil {
+			continue
+		}
+		path := fileHash.Path
+		if baseDir != "" {
+			abs, err := filepath.Abs(path)
+			if err == nil && abs == manifestAbs {
+				continue
+			}
+			if rel, err := filepath.Rel(baseDir, abs); err == nil {
+				path = rel
+			}
+		}
+		if tag != "" {
+			fmt.Fprintf(&builder, "%s (%s) = %s\n", tag, filepath.ToSlash(path), fileHash.Hash)
+		} else {
+			fmt.Fprintf(&builder, "%s  %s\n", fileHash.Hash, filepath.ToSlash(path))
+		}
+	}
+	if manifestPath == "-" {
+		_, err := os.Stdout.WriteString(builder.String())
+		return err
+	}
+	return writeFileAtomic(manifestPath, []byte(builder.String()), 0644)
+}
+
+// guessHashAlgorithm uses the manifest name (SHA256SUMS, files.md5, B3SUMS)
+// and falls back to the digest length
+func guessHashAlgorithm(manifestPath string, digestLength int) string {
+	name := strings.ToLower(filepath.Base(manifestPath))
+	for _, candidate := range []struct{ marker, algorithm string }{
+		{"sha512", HashSHA512}, {"sha256", HashSHA256}, {"sha1", HashSHA1}, {"md5", HashMD5},
+		{"blake2", HashBLAKE2b}, {"b2sum", HashBLAKE2b}, {"blake3", HashBLAKE3}, {"b3sum", HashBLAKE3},
+	} {
+		if strings.Contains(name, candidate.marker) {
+			return candidate.algorithm
+		}
+	}
+	switch digestLength {
+	case 32:
+		return HashMD5
+	case 40:
+		return HashSHA1
+	case 128:
+		return HashSHA512
+	default:
+		return HashSHA256
+	}
+}
+
+// ParseHashManifest reads GNU (`<hash>  <path>`) and BSD (`SHA256 (path) = hash`)
+// manifests and reports the algorithm the manifest appears to use
+func ParseHashManifest(manifestPath string) ([]ManifestEntry, string, error) {
+	file, err := os.Open(manifestPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer file.Close()
+	var entries []ManifestEntry
+	algorithm := ""
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if match := bsdManifestLine.FindStringSubmatch(line); match != nil {
+			if tagged, ok := hashTags[match[1]]; ok {
+				algorithm = tagged
+			}
+			entries = append(entries, ManifestEntry{Path: match[2], Hash: strings.ToLower(match[3])})
+			continue
+		}
+		match := gnuManifestLine.FindStringSubmatch(line)
+		if match == nil {
+			return nil, "", fmt.Errorf("malformed manifest line %d", lineNumber)
+		}
+		entries = append(entries, ManifestEntry{Path: match[2], Hash: strings.ToLower(match[1])})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, "", fmt.Errorf("no entries found in manifest")
+	}
+	if algorithm == "" {
+		algorithm = guessHashAlgorithm(manifestPath, len(entries[0].Hash))
+	}
+	return entries, algorithm, nil
+}
+
+// VerifyHashManifest re-hashes every entry relative to the manifest's
+// directory and lists files in that tree the manifest does not mention
+func VerifyHashManifest(manifestPath, algorithm string, workers int) ([]HashVerifyResult, string, error) {
+	entries, guessed, err := ParseHashManifest(manifestPath)
+	if err != nil {
+		return nil, "", err
+	}
+	if algorithm == "" {
+		algorithm = guessed
+	}
+	baseDir := filepath.Dir(manifestPath)
+	resolve := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(baseDir, filepath.FromSlash(path))
+	}
+
+	var results []HashVerifyResult
+	var present []ManifestEntry
+	listed := map[string]bool{}
+	for _, entry := range entries {
+		if abs, err := filepath.Abs(resolve(entry.Path)); err == nil {
+			listed[abs] = true
+		}
+		if _, err := os.Stat(resolve(entry.Path)); err != nil {
+			results = append(results, HashVerifyResult{Path: entry.Path, Status: HashStatusMissing, Expected: entry.Hash})
+			continue
+		}
+		present = append(present, entry)
+	}
+	files := make([]string, len(present))
+	for i, entry := range present {
+		files[i] = resolve(entry.Path)
+	}
+	hashes, err := hashFilesParallel(files, algorithm, workers)
+	if err != nil {
+		return nil, "", err
+	}
+	for i, entry := range present {
+		result := HashVerifyResult{Path: entry.Path, Expected: entry.Hash, Actual: hashes[i].Hash}
+		switch {
+		case hashes[i].Err != nil:
+			result.Status, result.Actual = HashStatusError, hashes[i].Err.Error()
+		case hashes[i].Hash == entry.Hash:
+			result.Status = HashStatusOK
+		default:
+			result.Status = HashStatusMismatch
+		}
+		results = append(results, result)
+	}
+
+	if abs, err := filepath.Abs(manifestPath); err == nil {
+		listed[abs] = true
+	}
+	filepath.WalkDir(baseDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if abs, _ := filepath.Abs(path); listed[abs] {
+			return nil
+		}
+		rel, err := filepath.Rel(baseDir, path)
+		if err != nil {
+			rel = path
+		}
+		results = append(results, HashVerifyResult{Path: filepath.ToSlash(rel), Status: HashStatusExtra})
+		return nil
+	})
+	slices.SortStableFunc(results, func(a, b HashVerifyResult) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return results, algorithm, nil
+}
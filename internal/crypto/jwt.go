@@ -0,0 +1,443 @@
+package anbuCrypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+var JWTTimeClaims = []string{"exp", "iat", "nbf"}
+
+type jwtAlgorithm struct {
+	family string
+	hash   crypto.Hash
+	curve  elliptic.Curve
+}
+
+var jwtAlgorithms = map[string]jwtAlgorithm{
+	"HS256": {"HS", crypto.SHA256, nil},
+	"HS384": {"HS", crypto.SHA384, nil},
+	"HS512": {"HS", crypto.SHA512, nil},
+	"RS256": {"RS", crypto.SHA256, nil},
+	"RS384": {"RS", crypto.SHA384, nil},
+	"RS512": {"RS", crypto.SHA512, nil},
+	"PS256": {"PS", crypto.SHA256, nil},
+	"PS384": {"PS", crypto.SHA384, nil},
+	"PS512": {"PS", crypto.SHA512, nil},
+	"ES256": {"ES", crypto.SHA256, elliptic.P256()},
+	"ES384": {"ES", crypto.SHA384, elliptic.P384()},
+	"ES512": {"ES", crypto.SHA512, elliptic.P521()},
+	"EdDSA": {"EdDSA", 0, nil},
+}
+
+type JWT struct {
+	Header       map[string]any
+	Claims       map[string]any
+	RawHeader    []byte
+	RawClaims    []byte
+	Signature    []byte
+	signingInput string
+}
+
+type JWTVerifyOptions struct {
+	KeyPath    string
+	Passphrase string
+	JWKSPath   string
+	Secret     string
+	Leeway     time.Duration
+}
+
+type JWTSignOptions struct {
+	Claims     []byte
+	KeyPath    string
+	Passphrase string
+	Secret     string
+	Algorithm  string
+	KeyID      string
+	ExpiresIn  time.Duration
+}
+
+func decodeJSONObject(data []byte) (map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var object map[string]any
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+	if object == nil {
+		return nil, fmt.Errorf("not a JSON object")
+	}
+	return object, nil
+}
+
+// ParseJWT decodes a compact JWS without checking its signature; a leading
+// "Bearer " is ignored so Authorization headers can be pasted as is
+func ParseJWT(token string) (*JWT, error) {
+	token = strings.TrimSpace(token)
+	token = strings.TrimSpace(strings.TrimPrefix(token, "Bearer "))
+	parts := strings.Split(token, ".")
+	if len(parts) == 5 {
+		return nil, fmt.Errorf("token is an encrypted JWE, only signed JWTs are supported")
+	}
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token has %d parts, expected 3", len(parts))
+	}
+	jwt := &JWT{signingInput: parts[0] + "." + parts[1]}
+	var err error
+	if jwt.RawHeader, err = base64.RawURLEncoding.DecodeString(parts[0]); err != nil {
+		return nil, fmt.Errorf("invalid header encoding: %w", err)
+	}
+	if jwt.RawClaims, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+		return nil, fmt.Errorf("invalid claims encoding: %w", err)
+	}
+	if jwt.Signature, err = base64.RawURLEncoding.DecodeString(parts[2]); err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	if jwt.Header, err = decodeJSONObject(jwt.RawHeader); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if jwt.Claims, err = decodeJSONObject(jwt.RawClaims); err != nil {
+		return nil, fmt.Errorf("invalid claims: %w", err)
+	}
+	return jwt, nil
+}
+
+func (j *JWT) headerString(name string) string {
+	value, _ := j.Header[name].(string)
+	return value
+}
+
+func (j *JWT) Algorithm() string {
+	return j.headerString("alg")
+}
+
+// TimeClaim returns a NumericDate claim such as exp, iat or nbf
+func (j *JWT) TimeClaim(name string) (time.Time, bool) {
+	number, ok := j.Claims[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := number.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	whole := int64(seconds)
+	return time.Unix(whole, int64((seconds-float64(whole))*1e9)), true
+}
+
+func jwtDigest(hash crypto.Hash, data string) []byte {
+	h := hash.New()
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// checkJWTKey refuses key and algorithm combinations that do not belong
+// together, which rules out the classic RS256/HS256 confusion
+func checkJWTKey(name string, alg jwtAlgorithm, key any) error {
+	ok := false
+	switch k := key.(type) {
+	case []byte:
+		ok = alg.family == "HS"
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		ok = alg.family == "RS" || alg.family == "PS"
+	case *ecdsa.PublicKey:
+		ok = alg.family == "ES" && k.Curve == alg.curve
+	case *ecdsa.PrivateKey:
+		ok = alg.family == "ES" && k.Curve == alg.curve
+	case ed25519.PublicKey, ed25519.PrivateKey:
+		ok = alg.family == "EdDSA"
+	}
+	if !ok {
+		return fmt.Errorf("%s cannot be used with this key", name)
+	}
+	return nil
+}
+
+func signJWTInput(name string, key any, signingInput string) ([]byte, error) {
+	alg, ok := jwtAlgorithms[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm '%s'", name)
+	}
+	if err := checkJWTKey(name, alg, key); err != nil {
+		return nil, err
+	}
+	switch alg.family {
+	case "HS":
+		mac := hmac.New(alg.hash.New, key.([]byte))
+		mac.Write([]byte(signingInput))
+		return mac.Sum(nil), nil
+	case "RS":
+		return rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), alg.hash, jwtDigest(alg.hash, signingInput))
+	case "PS":
+		return rsa.SignPSS(rand.Reader, key.(*rsa.PrivateKey), alg.hash, jwtDigest(alg.hash, signingInput), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES":
+		r, s, err := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), jwtDigest(alg.hash, signingInput))
+		if err != nil {
+			return nil, err
+		}
+		// JWS uses fixed-size r || s instead of ASN.1
+		size := (alg.curve.Params().BitSize + 7) / 8
+		signature := make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+		return signature, nil
+	default:
+		return ed25519.Sign(key.(ed25519.PrivateKey), []byte(signingInput)), nil
+	}
+}
+
+func verifyJWTSignature(name string, key any, signingInput string, signature []byte) error {
+	alg, ok := jwtAlgorithms[name]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm '%s'", name)
+	}
+	if err := checkJWTKey(name, alg, key); err != nil {
+		return err
+	}
+	valid := false
+	switch alg.family {
+	case "HS":
+		mac := hmac.New(alg.hash.New, key.([]byte))
+		mac.Write([]byte(signingInput))
+		valid = hmac.Equal(mac.Sum(nil), signature)
+	case "RS":
+		valid = rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), alg.hash, jwtDigest(alg.hash, signingInput), signature) == nil
+	case "PS":
+		valid = rsa.VerifyPSS(key.(*rsa.PublicKey), alg.hash, jwtDigest(alg.hash, signingInput), signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}) == nil
+	case "ES":
+		size := (alg.curve.Params().BitSize + 7) / 8
+		if len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			valid = ecdsa.Verify(key.(*ecdsa.PublicKey), jwtDigest(alg.hash, signingInput), r, s)
+		}
+	default:
+		valid = ed25519.Verify(key.(ed25519.PublicKey), []byte(signingInput), signature)
+	}
+	if !valid {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
+
+type jwtKey struct {
+	kid         string
+	key         any
+	description string
+}
+
+// loadJWKS reads a {"keys": [...]} document, skipping key types ParseJWK
+// does not understand
+func loadJWKS(path string) ([]jwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+	var keys []jwtKey
+	for i, raw := range set.Keys {
+		parsed, err := ParseJWK(raw)
+		if err != nil {
+			continue
+		}
+		if signer, ok := parsed.(crypto.Signer); ok {
+			parsed = signer.Public()
+		}
+		var meta struct {
+			Kid string `json:"kid"`
+		}
+		json.Unmarshal(raw, &meta)
+		description := fmt.Sprintf("JWKS key #%d", i+1)
+		if meta.Kid != "" {
+			description = fmt.Sprintf("JWKS key '%s'", meta.Kid)
+		}
+		keys = append(keys, jwtKey{kid: meta.Kid, key: parsed, description: description})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no usable keys in JWKS")
+	}
+	return keys, nil
+}
+
+func jwtVerificationKeys(jwt *JWT, opts JWTVerifyOptions) ([]jwtKey, error) {
+	var keys []jwtKey
+	if opts.Secret != "" {
+		keys = append(keys, jwtKey{key: []byte(opts.Secret), description: "shared secret"})
+	}
+	if opts.KeyPath != "" {
+		material, err := loadKeyMaterial(opts.KeyPath, opts.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, jwtKey{key: material.public, description: opts.KeyPath})
+	}
+	if opts.JWKSPath != "" {
+		set, err := loadJWKS(opts.JWKSPath)
+		if err != nil {
+			return nil, err
+		}
+		if kid := jwt.headerString("kid"); kid != "" {
+			var matching []jwtKey
+			for _, key := range set {
+				if key.kid == kid {
+					matching = append(matching, key)
+				}
+			}
+			if len(matching) == 0 {
+				return nil, fmt.Errorf("no key with kid '%s' in JWKS", kid)
+			}
+			set = matching
+		}
+		keys = append(keys, set...)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("a key, JWKS or secret is required")
+	}
+	return keys, nil
+}
+
+// VerifyJWT checks the signature offline against a key file, a JWKS or an
+// HMAC secret, then the exp and nbf claims. It returns the key that matched
+func VerifyJWT(token string, opts JWTVerifyOptions) (*JWT, string, error) {
+	jwt, err := ParseJWT(token)
+	if err != nil {
+		return nil, "", err
+	}
+	name := jwt.Algorithm()
+	if name == "" || strings.EqualFold(name, "none") {
+		return jwt, "", fmt.Errorf("unsigned tokens (alg '%s') are never accepted", name)
+	}
+	if _, ok := jwtAlgorithms[name]; !ok {
+		return jwt, "", fmt.Errorf("unsupported algorithm '%s'", name)
+	}
+	keys, err := jwtVerificationKeys(jwt, opts)
+	if err != nil {
+		return jwt, "", err
+	}
+	matched := ""
+	var lastErr error
+	for _, key := range keys {
+		if lastErr = verifyJWTSignature(name, key.key, jwt.signingInput, jwt.Signature); lastErr == nil {
+			matched = key.description
+			break
+		}
+	}
+	if matched == "" {
+		if len(keys) > 1 {
+			return jwt, "", fmt.Errorf("no key verified the signature")
+		}
+		return jwt, "", lastErr
+	}
+	now := time.Now()
+	if exp, ok := jwt.TimeClaim("exp"); ok && now.After(exp.Add(opts.Leeway)) {
+		return jwt, matched, fmt.Errorf("token expired at %s", exp.UTC().Format(time.RFC3339))
+	}
+	if nbf, ok := jwt.TimeClaim("nbf"); ok && now.Before(nbf.Add(-opts.Leeway)) {
+		return jwt, matched, fmt.Errorf("token is not valid before %s", nbf.UTC().Format(time.RFC3339))
+	}
+	return jwt, matched, nil
+}
+
+func defaultJWTAlgorithm(key any) (string, error) {
+	switch k := key.(type) {
+	case []byte:
+		return "HS256", nil
+	case *rsa.PrivateKey:
+		return "RS256", nil
+	case *ecdsa.PrivateKey:
+		for name, alg := range jwtAlgorithms {
+			if alg.curve == k.Curve {
+				return name, nil
+			}
+		}
+	case ed25519.PrivateKey:
+		return "EdDSA", nil
+	}
+	return "", fmt.Errorf("unsupported signing key %T", key)
+}
+
+// SignJWT mints a compact JWS from a JSON claims object, adding iat and,
+// when ExpiresIn is set, exp
+func SignJWT(opts JWTSignOptions) (string, error) {
+	var key any
+	switch {
+	case opts.Secret != "" && opts.KeyPath != "":
+		return "", fmt.Errorf("use either a key or a secret, not both")
+	case opts.Secret != "":
+		key = []byte(opts.Secret)
+	case opts.KeyPath != "":
+		material, err := loadKeyMaterial(opts.KeyPath, opts.Passphrase)
+		if err != nil {
+			return "", err
+		}
+		if material.private == nil {
+			if material.encrypted {
+				return "", ErrPassphraseRequired
+			}
+			return "", fmt.Errorf("%s is a public key, signing needs the private key", opts.KeyPath)
+		}
+		key = material.private
+	default:
+		return "", fmt.Errorf("a key or secret is required")
+	}
+	name := opts.Algorithm
+	if name == "" {
+		var err error
+		if name, err = defaultJWTAlgorithm(key); err != nil {
+			return "", err
+		}
+	}
+	claims := map[string]any{}
+	if len(bytes.TrimSpace(opts.Claims)) > 0 {
+		var err error
+		if claims, err = decodeJSONObject(opts.Claims); err != nil {
+			return "", fmt.Errorf("invalid claims: %w", err)
+		}
+	}
+	now := time.Now()
+	if _, ok := claims["iat"]; !ok {
+		claims["iat"] = now.Unix()
+	}
+	if opts.ExpiresIn > 0 {
+		claims["exp"] = now.Add(opts.ExpiresIn).Unix()
+	}
+	header, err := json.Marshal(struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid,omitempty"`
+		Typ string `json:"typ"`
+	}{name, opts.KeyID, "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+	signingInput := b64url(header) + "." + b64url(payload)
+	signature, err := signJWTInput(name, key, signingInput)
+	if err != nil {
+		if errors.Is(err, rsa.ErrMessageTooLong) {
+			return "", fmt.Errorf("RSA key is too small for %s", name)
+		}
+		return "", err
+	}
+	return signingInput + "." + b64url(signature), nil
+}
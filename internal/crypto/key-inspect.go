@@ -0,0 +1,275 @@
+package anbuCrypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var KeyConvertFormats = []string{KeyFormatOpenSSH, KeyFormatPKCS8, KeyFormatPKCS1, KeyFormatJWK}
+
+type keyMaterial struct {
+	private   crypto.Signer
+	public    crypto.PublicKey
+	format    string
+	encrypted bool
+	comment   string
+}
+
+// loadKeyMaterial accepts private keys in any format LoadPrivateKeyFile reads,
+// PEM public keys, authorized_keys lines and JWKs. Encrypted OpenSSH keys
+// still expose their public half without a passphrase
+func loadKeyMaterial(path, passphrase string) (*keyMaterial, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("{")) {
+		parsed, err := ParseJWK(trimmed)
+		if err != nil {
+			return nil, err
+		}
+		if signer, ok := parsed.(crypto.Signer); ok {
+			return &keyMaterial{private: signer, public: signer.Public(), format: KeyFormatJWK}, nil
+		}
+		return &keyMaterial{public: parsed, format: KeyFormatJWK}, nil
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		publicKey, comment, _, _, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("unrecognized key format")
+		}
+		return sshPublicKeyMaterial(publicKey, comment)
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+		return &keyMaterial{public: publicKey, format: KeyFormatPKIX}, nil
+	case "RSA PUBLIC KEY":
+		publicKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+		return &keyMaterial{public: publicKey, format: KeyFormatPKCS1}, nil
+	case "OPENSSH PRIVATE KEY":
+		if passphrase == "" {
+			_, err := ssh.ParseRawPrivateKey(data)
+			var missing *ssh.PassphraseMissingError
+			if errors.As(err, &missing) {
+				key, err := sshPublicKeyMaterial(missing.PublicKey, publicKeyFileComment(path+".pub"))
+				if err != nil {
+					return nil, err
+				}
+				key.format, key.encrypted = KeyFormatOpenSSH, true
+				return key, nil
+			}
+		}
+	}
+	keyFile, err := LoadPrivateKeyFile(path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &keyMaterial{
+		private:   keyFile.Key,
+		public:    keyFile.Key.Public(),
+		format:    keyFile.Format,
+		encrypted: keyFile.Encrypted,
+		comment:   keyFile.Comment,
+	}, nil
+}
+
+func sshPublicKeyMaterial(publicKey ssh.PublicKey, comment string) (*keyMaterial, error) {
+	cryptoKey, ok := publicKey.(ssh.CryptoPublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported SSH key type '%s'", publicKey.Type())
+	}
+	return &keyMaterial{public: cryptoKey.CryptoPublicKey(), format: KeyFormatOpenSSH, comment: comment}, nil
+}
+
+func publicKeyAlgorithm(publicKey crypto.PublicKey) (string, int, error) {
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		return KeyTypeRSA, k.N.BitLen(), nil
+	case *ecdsa.PublicKey:
+		params := k.Curve.Params()
+		return "ecdsa-" + strings.ToLower(strings.ReplaceAll(params.Name, "-", "")), params.BitSize, nil
+	case ed25519.PublicKey:
+		return KeyTypeEd25519, 256, nil
+	default:
+		return "", 0, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+}
+
+func keyMaterialResult(key *keyMaterial, path string) (*KeyPairResult, error) {
+	keyType, bits, err := publicKeyAlgorithm(key.public)
+	if err != nil {
+		return nil, err
+	}
+	sshPublicKey, err := ssh.NewPublicKey(key.public)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH public key: %w", err)
+	}
+	result := &KeyPairResult{
+		Type:           keyType,
+		KeySize:        bits,
+		Fingerprint:    ssh.FingerprintSHA256(sshPublicKey),
+		FingerprintMD5: ssh.FingerprintLegacyMD5(sshPublicKey),
+		Format:         key.format,
+		Comment:        key.comment,
+		Private:        key.private != nil || key.encrypted,
+		Encrypted:      key.encrypted,
+	}
+	if result.Private {
+		result.PrivateKeyPath = path
+	} else {
+		result.PublicKeyPath = path
+	}
+	return result, nil
+}
+
+func InspectKey(path, passphrase string) (*KeyPairResult, error) {
+	key, err := loadKeyMaterial(path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return keyMaterialResult(key, path)
+}
+
+func marshalPublicKey(publicKey crypto.PublicKey, format, comment string) ([]byte, error) {
+	switch format {
+	case KeyFormatOpenSSH:
+		sshPublicKey, err := ssh.NewPublicKey(publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SSH public key: %w", err)
+		}
+		return marshalAuthorizedKey(sshPublicKey, comment), nil
+	case KeyFormatPKCS8:
+		der, err := x509.MarshalPKIXPublicKey(publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal public key: %w", err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+	case KeyFormatPKCS1:
+		rsaKey, ok := publicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("PKCS#1 only holds RSA keys")
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(rsaKey)}), nil
+	case KeyFormatJWK:
+		jwk, err := publicJWK(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		return marshalJWK(jwk)
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+}
+
+// marshalPrivateKey keeps an encrypted input encrypted with the same
+// passphrase; PKCS#1 and JWK have no encrypted form so they refuse
+func marshalPrivateKey(key crypto.Signer, format, comment, passphrase string) ([]byte, error) {
+	switch format {
+	case KeyFormatOpenSSH:
+		block, err := marshalOpenSSHPrivateKey(key, comment, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal OpenSSH private key: %w", err)
+		}
+		return pem.EncodeToMemory(block), nil
+	case KeyFormatPKCS8:
+		block, err := marshalPEMPrivateKey(key, true, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(block), nil
+	}
+	if passphrase != "" {
+		return nil, fmt.Errorf("%s cannot hold an encrypted key, remove the passphrase first", format)
+	}
+	switch format {
+	case KeyFormatPKCS1:
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("PKCS#1 only holds RSA keys")
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), nil
+	case KeyFormatJWK:
+		jwk, err := privateJWK(key)
+		if err != nil {
+			return nil, err
+		}
+		return marshalJWK(jwk)
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+}
+
+func marshalJWK(jwk *JWK) ([]byte, error) {
+	data, err := json.MarshalIndent(jwk, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JWK: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// ConvertKey re-serializes a key into format, writing to stdout when
+// outputPath is empty. Public keys and publicOnly conversions only emit
+// the public half
+func ConvertKey(inputPath, outputPath, format, passphrase string, publicOnly bool) (*KeyPairResult, error) {
+	if !slices.Contains(KeyConvertFormats, format) {
+		return nil, fmt.Errorf("unsupported format '%s', use one of %s", format, strings.Join(KeyConvertFormats, ", "))
+	}
+	key, err := loadKeyMaterial(inputPath, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if key.private == nil && key.encrypted && !publicOnly {
+		return nil, ErrPassphraseRequired
+	}
+	if !key.encrypted {
+		passphrase = ""
+	}
+	var data []byte
+	perm := os.FileMode(0644)
+	if key.private != nil && !publicOnly {
+		data, err = marshalPrivateKey(key.private, format, key.comment, passphrase)
+		perm = 0600
+	} else {
+		data, err = marshalPublicKey(key.public, format, key.comment)
+	}
+	if err != nil {
+		return nil, err
+	}
+	converted := &keyMaterial{format: format, comment: key.comment, public: key.public, encrypted: perm == 0600 && passphrase != ""}
+	if perm == 0600 {
+		converted.private = key.private
+	}
+	result, err := keyMaterialResult(converted, outputPath)
+	if err != nil {
+		return nil, err
+	}
+	if outputPath == "" {
+		_, err = os.Stdout.Write(data)
+		return result, err
+	}
+	if err := writeFileAtomic(outputPath, data, perm); err != nil {
+		return nil, fmt.Errorf("failed to write converted key: %w", err)
+	}
+	return result, nil
+}
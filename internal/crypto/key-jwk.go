@@ -0,0 +1,237 @@
+package anbuCrypto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// JWK is the RFC 7517 JSON Web Key form of RSA, EC and OKP (Ed25519) keys
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	D   string `json:"d,omitempty"`
+	P   string `json:"p,omitempty"`
+	Q   string `json:"q,omitempty"`
+	DP  string `json:"dp,omitempty"`
+	DQ  string `json:"dq,omitempty"`
+	QI  string `json:"qi,omitempty"`
+}
+
+var jwkCurves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+func b64url(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func b64urlInt(n *big.Int) string {
+	return b64url(n.Bytes())
+}
+
+func publicJWK(pub crypto.PublicKey) (*JWK, error) {
+	var jwk *JWK
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		jwk = &JWK{Kty: "RSA", N: b64urlInt(k.N), E: b64urlInt(big.NewInt(int64(k.E)))}
+	case *ecdsa.PublicKey:
+		point, err := k.Bytes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode EC public key: %w", err)
+		}
+		size := (len(point) - 1) / 2
+		jwk = &JWK{Kty: "EC", Crv: k.Curve.Params().Name, X: b64url(point[1 : 1+size]), Y: b64url(point[1+size:])}
+	case ed25519.PublicKey:
+		jwk = &JWK{Kty: "OKP", Crv: "Ed25519", X: b64url(k)}
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", pub)
+	}
+	jwk.Kid = jwk.thumbprint()
+	return jwk, nil
+}
+
+func privateJWK(key crypto.Signer) (*JWK, error) {
+	jwk, err := publicJWK(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if len(k.Primes) != 2 {
+			return nil, fmt.Errorf("multi-prime RSA keys are not supported")
+		}
+		k.Precompute()
+		jwk.D = b64urlInt(k.D)
+		jwk.P = b64urlInt(k.Primes[0])
+		jwk.Q = b64urlInt(k.Primes[1])
+		jwk.DP = b64urlInt(k.Precomputed.Dp)
+		jwk.DQ = b64urlInt(k.Precomputed.Dq)
+		jwk.QI = b64urlInt(k.Precomputed.Qinv)
+	case *ecdsa.PrivateKey:
+		d, err := k.Bytes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode EC private key: %w", err)
+		}
+		jwk.D = b64url(d)
+	case ed25519.PrivateKey:
+		jwk.D = b64url(k.Seed())
+	}
+	return jwk, nil
+}
+
+// thumbprint is the RFC 7638 SHA-256 thumbprint over the required members
+func (j *JWK) thumbprint() string {
+	var members string
+	switch j.Kty {
+	case "RSA":
+		members = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, j.E, j.N)
+	case "EC":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, j.Crv, j.X, j.Y)
+	case "OKP":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, j.Crv, j.X)
+	}
+	sum := sha256.Sum256([]byte(members))
+	return b64url(sum[:])
+}
+
+func decodeJWKField(name, value string) ([]byte, error) {
+	if value == "" {
+		return nil, fmt.Errorf("JWK is missing '%s'", name)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWK '%s': %w", name, err)
+	}
+	return data, nil
+}
+
+func decodeJWKInt(name, value string) (*big.Int, error) {
+	data, err := decodeJWKField(name, value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// ParseJWK returns a crypto.Signer for private keys and a crypto.PublicKey otherwise
+func ParseJWK(data []byte) (any, error) {
+	var jwk JWK
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, fmt.Errorf("failed to parse JWK: %w", err)
+	}
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeJWKInt("n", jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt("e", jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		pub := rsa.PublicKey{N: n, E: int(e.Int64())}
+		if jwk.D == "" {
+			return &pub, nil
+		}
+		key := &rsa.PrivateKey{PublicKey: pub}
+		if key.D, err = decodeJWKInt("d", jwk.D); err != nil {
+			return nil, err
+		}
+		p, err := decodeJWKInt("p", jwk.P)
+		if err != nil {
+			return nil, err
+		}
+		q, err := decodeJWKInt("q", jwk.Q)
+		if err != nil {
+			return nil, err
+		}
+		key.Primes = []*big.Int{p, q}
+		if err := key.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid RSA key: %w", err)
+		}
+		key.Precompute()
+		return key, nil
+	case "EC":
+		curve, ok := jwkCurves[jwk.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve '%s'", jwk.Crv)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		x, err := decodeJWKField("x", jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKField("y", jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("invalid EC coordinates")
+		}
+		pub, err := ecdsa.ParseUncompressedPublicKey(curve, append(append([]byte{4}, x...), y...))
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC public key: %w", err)
+		}
+		if jwk.D == "" {
+			return pub, nil
+		}
+		d, err := decodeJWKField("d", jwk.D)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ecdsa.ParseRawPrivateKey(curve, d)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC private key: %w", err)
+		}
+		if !key.PublicKey.Equal(pub) {
+			return nil, fmt.Errorf("EC private key does not match its public key")
+		}
+		return key, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve '%s'", jwk.Crv)
+		}
+		x, err := decodeJWKField("x", jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 public key")
+		}
+		if jwk.D == "" {
+			return ed25519.PublicKey(x), nil
+		}
+		seed, err := decodeJWKField("d", jwk.D)
+		if err != nil {
+			return nil, err
+		}
+		if len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid Ed25519 private key")
+		}
+		key := ed25519.NewKeyFromSeed(seed)
+		if !key.Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(x)) {
+			return nil, fmt.Errorf("Ed25519 private key does not match its public key")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWK key type '%s'", jwk.Kty)
+	}
+}
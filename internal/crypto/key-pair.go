@@ -1,6 +1,10 @@
 package anbuCrypto
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -8,42 +12,136 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"golang.org/x/crypto/ssh"
 )
 
+const (
+	KeyTypeRSA       = "rsa"
+	KeyTypeEd25519   = "ed25519"
+	KeyTypeECDSAP256 = "ecdsa-p256"
+	KeyTypeECDSAP384 = "ecdsa-p384"
+)
+
+var SupportedKeyTypes = []string{KeyTypeEd25519, KeyTypeECDSAP256, KeyTypeECDSAP384, KeyTypeRSA}
+
+type KeyPairOptions struct {
+	Type       string
+	KeySize    int
+	Comment    string
+	PKCS8      bool
+	Passphrase string
+}
+
 type KeyPairResult struct {
+	Type           string
 	KeySize        int
+	Fingerprint    string
+	FingerprintMD5 string
+	Format         string
+	Comment        string
+	Private        bool
+	Encrypted      bool
 	PublicKeyPath  string
 	PrivateKeyPath string
 }
 
-func GenerateKeyPair(outputDir, name string, keySize int) (*KeyPairResult, error) {
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create output directory: %w", err)
+func generatePrivateKey(opts KeyPairOptions) (crypto.Signer, int, error) {
+	switch opts.Type {
+	case KeyTypeRSA, "":
+		if opts.KeySize < 2048 {
+			return nil, 0, fmt.Errorf("RSA keys must be at least 2048 bits")
+		}
+		key, err := rsa.GenerateKey(rand.Reader, opts.KeySize)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to generate RSA key: %w", err)
+		}
+		return key, opts.KeySize, nil
+	case KeyTypeEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to generate Ed25519 key: %w", err)
+		}
+		return key, 256, nil
+	case KeyTypeECDSAP256, KeyTypeECDSAP384:
+		curve, bits := elliptic.P256(), 256
+		if opts.Type == KeyTypeECDSAP384 {
+			curve, bits = elliptic.P384(), 384
+		}
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to generate ECDSA key: %w", err)
+		}
+		return key, bits, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported key type '%s'", opts.Type)
+	}
+}
+
+// Ed25519 only has a PKCS#8 encoding; RSA and ECDSA default to their
+// traditional PKCS#1 and SEC 1 forms unless PKCS#8 is requested. Encrypted
+// keys are always PKCS#8 since the legacy PEM encryption is unauthenticated
+func marshalPEMPrivateKey(key crypto.Signer, pkcs8 bool, passphrase string) (*pem.Block, error) {
+	if passphrase != "" {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal PKCS#8 private key: %w", err)
+		}
+		defer clear(der)
+		return encryptPKCS8(der, passphrase)
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if !pkcs8 {
+			return &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}, nil
+		}
+	case *ecdsa.PrivateKey:
+		if !pkcs8 {
+			der, err := x509.MarshalECPrivateKey(k)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal EC private key: %w", err)
+			}
+			return &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}, nil
+		}
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal PKCS#8 private key: %w", err)
 	}
+	return &pem.Block{Type: "PRIVATE KEY", Bytes: der}, nil
+}
 
-	privateKey, err := rsa.GenerateKey(rand.Reader, keySize)
+func writePEMFile(path string, block *pem.Block, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+		return err
 	}
-	publicKey := &privateKey.PublicKey
-	privateKeyBlock := &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	if err := pem.Encode(file, block); err != nil {
+		file.Close()
+		return err
 	}
-	privateKeyPath := filepath.Join(outputDir, name+".private.pem")
-	privateKeyFile, err := os.OpenFile(privateKeyPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	return file.Close()
+}
+
+func GenerateKeyPair(outputDir, name string, opts KeyPairOptions) (*KeyPairResult, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	privateKey, bits, err := generatePrivateKey(opts)
+	if err != nil {
+		return nil, err
+	}
+	privateKeyBlock, err := marshalPEMPrivateKey(privateKey, opts.PKCS8, opts.Passphrase)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create private key file: %w", err)
+		return nil, err
 	}
-	if err := pem.Encode(privateKeyFile, privateKeyBlock); err != nil {
-		privateKeyFile.Close()
+	privateKeyPath := filepath.Join(outputDir, name+".private.pem")
+	if err := writePEMFile(privateKeyPath, privateKeyBlock, 0600); err != nil {
 		return nil, fmt.Errorf("failed to write private key to file: %w", err)
 	}
-	privateKeyFile.Close()
 
-	publicKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(privateKey.Public())
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal public key: %w", err)
 	}
@@ -52,59 +150,90 @@ func GenerateKeyPair(outputDir, name string, keySize int) (*KeyPairResult, error
 		Bytes: publicKeyBytes,
 	}
 	publicKeyPath := filepath.Join(outputDir, name+".public.pem")
-	publicKeyFile, err := os.Create(publicKeyPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create public key file: %w", err)
-	}
-	if err := pem.Encode(publicKeyFile, publicKeyBlock); err != nil {
-		publicKeyFile.Close()
+	if err := writePEMFile(publicKeyPath, publicKeyBlock, 0644); err != nil {
 		return nil, fmt.Errorf("failed to write public key to file: %w", err)
 	}
-	publicKeyFile.Close()
 
+	sshPublicKey, err := ssh.NewPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH public key: %w", err)
+	}
 	return &KeyPairResult{
-		KeySize:        keySize,
+		Type:           keyTypeName(opts.Type),
+		KeySize:        bits,
+		Fingerprint:    ssh.FingerprintSHA256(sshPublicKey),
 		PublicKeyPath:  publicKeyPath,
 		PrivateKeyPath: privateKeyPath,
 	}, nil
 }
 
-func GenerateSSHKeyPair(outputDir, name string, keySize int) (*KeyPairResult, error) {
+func GenerateSSHKeyPair(outputDir, name string, opts KeyPairOptions) (*KeyPairResult, error) {
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create output directory: %w", err)
 	}
-	privateKey, err := rsa.GenerateKey(rand.Reader, keySize)
+	privateKey, bits, err := generatePrivateKey(opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+		return nil, err
 	}
-	publicKey, err := ssh.NewPublicKey(&privateKey.PublicKey)
+	publicKey, err := ssh.NewPublicKey(privateKey.Public())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SSH public key: %w", err)
 	}
-	sshPublicKeyBytes := ssh.MarshalAuthorizedKey(publicKey)
-
 	publicKeyPath := filepath.Join(outputDir, name+".pub")
-	if err := os.WriteFile(publicKeyPath, sshPublicKeyBytes, 0644); err != nil {
+	if err := os.WriteFile(publicKeyPath, marshalAuthorizedKey(publicKey, opts.Comment), 0644); err != nil {
 		return nil, fmt.Errorf("failed to write SSH public key file: %w", err)
 	}
-	privatePEM := &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-	}
-	privateKeyPath := filepath.Join(outputDir, name)
-	privateKeyFile, err := os.OpenFile(privateKeyPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	privatePEM, err := marshalOpenSSHPrivateKey(privateKey, opts.Comment, opts.Passphrase)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create private key file: %w", err)
+		return nil, fmt.Errorf("failed to marshal OpenSSH private key: %w", err)
 	}
-	if err := pem.Encode(privateKeyFile, privatePEM); err != nil {
-		privateKeyFile.Close()
+	privateKeyPath := filepath.Join(outputDir, name)
+	if err := writePEMFile(privateKeyPath, privatePEM, 0600); err != nil {
 		return nil, fmt.Errorf("failed to write private key to file: %w", err)
 	}
-	privateKeyFile.Close()
 
 	return &KeyPairResult{
-		KeySize:        keySize,
+		Type:           keyTypeName(opts.Type),
+		KeySize:        bits,
+		Fingerprint:    ssh.FingerprintSHA256(publicKey),
 		PublicKeyPath:  publicKeyPath,
 		PrivateKeyPath: privateKeyPath,
 	}, nil
 }
+
+// marshalOpenSSHPrivateKey protects the key with bcrypt-pbkdf and AES-256-CTR
+// when a passphrase is given, as ssh-keygen does
+func marshalOpenSSHPrivateKey(key crypto.PrivateKey, comment, passphrase string) (*pem.Block, error) {
+	if passphrase == "" {
+		return ssh.MarshalPrivateKey(key, comment)
+	}
+	return ssh.MarshalPrivateKeyWithPassphrase(key, comment, []byte(passphrase))
+}
+
+func marshalAuthorizedKey(publicKey ssh.PublicKey, comment string) []byte {
+	line := strings.TrimSuffix(string(ssh.MarshalAuthorizedKey(publicKey)), "\n")
+	if comment != "" {
+		line += " " + comment
+	}
+	return []byte(line + "\n")
+}
+
+func keyTypeName(keyType string) string {
+	if keyType == "" {
+		return KeyTypeRSA
+	}
+	return keyType
+}
+
+// DefaultKeyComment mirrors ssh-keygen's user@host comment
+func DefaultKeyComment() string {
+	user := os.Getenv("USER")
+	if user == "" {
+		user = os.Getenv("USERNAME")
+	}
+	host, _ := os.Hostname()
+	if user == "" || host == "" {
+		return ""
+	}
+	return user + "@" + host
+}
@@ -0,0 +1,160 @@
+package anbuCrypto
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	KeyFormatOpenSSH = "openssh"
+	KeyFormatPKCS1   = "pkcs1"
+	KeyFormatSEC1    = "sec1"
+	KeyFormatPKCS8   = "pkcs8"
+	KeyFormatPKIX    = "pkix"
+	KeyFormatJWK     = "jwk"
+)
+
+var ErrPassphraseRequired = errors.New("key is encrypted, a passphrase is required")
+
+type PrivateKeyFile struct {
+	Key       crypto.Signer
+	Format    string
+	Encrypted bool
+	Comment   string
+}
+
+func readPrivateKeyPEM(path string) (*pem.Block, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	return block, data, nil
+}
+
+func privateKeyFormat(block *pem.Block) (string, error) {
+	switch block.Type {
+	case "OPENSSH PRIVATE KEY":
+		return KeyFormatOpenSSH, nil
+	case "RSA PRIVATE KEY":
+		return KeyFormatPKCS1, nil
+	case "EC PRIVATE KEY":
+		return KeyFormatSEC1, nil
+	case "PRIVATE KEY", "ENCRYPTED PRIVATE KEY":
+		return KeyFormatPKCS8, nil
+	default:
+		return "", fmt.Errorf("unsupported PEM block '%s'", block.Type)
+	}
+}
+
+// KeyFileEncrypted reports whether a private key file needs a passphrase
+func KeyFileEncrypted(path string) (bool, error) {
+	block, data, err := readPrivateKeyPEM(path)
+	if err != nil {
+		return false, err
+	}
+	if _, err := privateKeyFormat(block); err != nil {
+		return false, err
+	}
+	if block.Type == "ENCRYPTED PRIVATE KEY" || block.Headers["Proc-Type"] == "4,ENCRYPTED" {
+		return true, nil
+	}
+	if block.Type == "OPENSSH PRIVATE KEY" {
+		_, err := ssh.ParseRawPrivateKey(data)
+		var missing *ssh.PassphraseMissingError
+		return errors.As(err, &missing), nil
+	}
+	return false, nil
+}
+
+func LoadPrivateKeyFile(path, passphrase string) (*PrivateKeyFile, error) {
+	block, data, err := readPrivateKeyPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	format, err := privateKeyFormat(block)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := KeyFileEncrypted(path)
+	if err != nil {
+		return nil, err
+	}
+	if encrypted && passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+	var raw any
+	switch {
+	case block.Type == "ENCRYPTED PRIVATE KEY":
+		raw, err = decryptPKCS8(block.Bytes, passphrase)
+	case encrypted:
+		raw, err = ssh.ParseRawPrivateKeyWithPassphrase(data, []byte(passphrase))
+	default:
+		raw, err = ssh.ParseRawPrivateKey(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	if k, ok := raw.(*ed25519.PrivateKey); ok {
+		raw = *k
+	}
+	signer, ok := raw.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", raw)
+	}
+	keyFile := &PrivateKeyFile{Key: signer, Format: format, Encrypted: encrypted}
+	if format == KeyFormatOpenSSH {
+		keyFile.Comment = publicKeyFileComment(path + ".pub")
+	}
+	return keyFile, nil
+}
+
+// The ssh package drops the comment stored inside OpenSSH private keys, so it
+// is recovered from the matching .pub file when one sits next to the key
+func publicKeyFileComment(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	_, comment, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(comment)
+}
+
+// ChangeKeyPassphrase re-encrypts a private key in place; an empty new
+// passphrase removes the encryption. PEM keys gaining a passphrase become
+// encrypted PKCS#8, OpenSSH keys stay OpenSSH
+func ChangeKeyPassphrase(path, oldPassphrase, newPassphrase string) error {
+	keyFile, err := LoadPrivateKeyFile(path, oldPassphrase)
+	if err != nil {
+		return err
+	}
+	if !keyFile.Encrypted && newPassphrase == "" {
+		return fmt.Errorf("key is not encrypted")
+	}
+	var block *pem.Block
+	if keyFile.Format == KeyFormatOpenSSH {
+		block, err = marshalOpenSSHPrivateKey(keyFile.Key, keyFile.Comment, newPassphrase)
+	} else {
+		block, err = marshalPEMPrivateKey(keyFile.Key, keyFile.Format == KeyFormatPKCS8, newPassphrase)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	if err := writeFileAtomic(path, pem.EncodeToMemory(block), 0600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	return nil
+}
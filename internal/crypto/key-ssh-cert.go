@@ -0,0 +1,241 @@
+package anbuCrypto
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// OpenSSH grants these to user certificates unless -O clear is given
+var defaultSSHCertExtensions = []string{
+	"permit-X11-forwarding",
+	"permit-agent-forwarding",
+	"permit-port-forwarding",
+	"permit-pty",
+	"permit-user-rc",
+}
+
+type SSHCertOptions struct {
+	CAKeyPath    string
+	CAPassphrase string
+	KeyID        string
+	Principals   []string
+	AnyPrincipal bool
+	Validity     string
+	Host         bool
+	Options      []string
+	Serial       uint64
+	OutputPath   string
+}
+
+type SSHCertResult struct {
+	CertPath      string
+	Type          string
+	KeyID         string
+	Serial        uint64
+	Principals    []string
+	ValidAfter    time.Time
+	ValidBefore   time.Time
+	Forever       bool
+	Extensions    []string
+	Critical      []string
+	CAFingerprint string
+}
+
+// parseSSHValidity accepts Go durations plus d and w suffixes, or "forever"
+func parseSSHValidity(validity string) (time.Duration, bool, error) {
+	switch strings.ToLower(validity) {
+	case "forever", "always", "":
+		return 0, true, nil
+	}
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(validity, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(validity, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+	if unit != 0 {
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(validity, "d"), "w"))
+		if err != nil || n <= 0 {
+			return 0, false, fmt.Errorf("invalid validity '%s'", validity)
+		}
+		return time.Duration(n) * unit, false, nil
+	}
+	d, err := time.ParseDuration(validity)
+	if err != nil || d <= 0 {
+		return 0, false, fmt.Errorf("invalid validity '%s'", validity)
+	}
+	return d, false, nil
+}
+
+// applySSHCertOptions follows ssh-keygen -O: clear, permit-*/no-*,
+// force-command=, source-address=, verify-required, no-touch-required and
+// the generic extension:name[=value] and critical:name[=value]
+func applySSHCertOptions(perms *ssh.Permissions, options []string) error {
+	for _, option := range options {
+		name, value, _ := strings.Cut(option, "=")
+		switch {
+		case option == "clear":
+			clear(perms.Extensions)
+		case strings.HasPrefix(name, "permit-"):
+			perms.Extensions[sshExtensionName(strings.TrimPrefix(name, "permit-"))] = ""
+		case strings.HasPrefix(name, "no-") && name != "no-touch-required":
+			delete(perms.Extensions, sshExtensionName(strings.TrimPrefix(name, "no-")))
+		case name == "no-touch-required":
+			perms.Extensions[name] = ""
+		case name == "force-command" || name == "source-address":
+			if value == "" {
+				return fmt.Errorf("option '%s' needs a value", name)
+			}
+			perms.CriticalOptions[name] = value
+		case name == "verify-required":
+			perms.CriticalOptions[name] = ""
+		case strings.HasPrefix(name, "extension:"):
+			perms.Extensions[strings.TrimPrefix(name, "extension:")] = value
+		case strings.HasPrefix(name, "critical:"):
+			perms.CriticalOptions[strings.TrimPrefix(name, "critical:")] = value
+		default:
+			return fmt.Errorf("unknown certificate option '%s'", option)
+		}
+	}
+	return nil
+}
+
+func sshExtensionName(feature string) string {
+	if feature == "x11-forwarding" {
+		return "permit-X11-forwarding"
+	}
+	return "permit-" + feature
+}
+
+func sshCertOutputPath(publicKeyPath string) string {
+	return strings.TrimSuffix(publicKeyPath, ".pub") + "-cert.pub"
+}
+
+// SignSSHCertificate signs an OpenSSH public key as a user or host
+// certificate, written next to the key as <name>-cert.pub by default
+func SignSSHCertificate(publicKeyPath string, opts SSHCertOptions) (*SSHCertResult, error) {
+	data, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+	publicKey, comment, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	if _, isCert := publicKey.(*ssh.Certificate); isCert {
+		return nil, fmt.Errorf("%s is already a certificate", publicKeyPath)
+	}
+	// sshd accepts a user certificate without principals for every account
+	if !opts.Host && len(opts.Principals) == 0 && !opts.AnyPrincipal {
+		return nil, fmt.Errorf("user certificates need principals, an empty list is valid for any user")
+	}
+	caKeyFile, err := LoadPrivateKeyFile(opts.CAKeyPath, opts.CAPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	caSigner, err := ssh.NewSignerFromKey(caKeyFile.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use CA key: %w", err)
+	}
+	validity, forever, err := parseSSHValidity(opts.Validity)
+	if err != nil {
+		return nil, err
+	}
+
+	cert := &ssh.Certificate{
+		Key:             publicKey,
+		Serial:          opts.Serial,
+		CertType:        ssh.UserCert,
+		KeyId:           opts.KeyID,
+		ValidPrincipals: opts.Principals,
+		Permissions: ssh.Permissions{
+			CriticalOptions: map[string]string{},
+			Extensions:      map[string]string{},
+		},
+	}
+	if cert.KeyId == "" {
+		cert.KeyId = comment
+	}
+	if cert.KeyId == "" {
+		cert.KeyId = filepath.Base(publicKeyPath)
+	}
+	if cert.Serial == 0 {
+		var buf [8]byte
+		if _, err := rand.Read(buf[:]); err != nil {
+			return nil, fmt.Errorf("failed to generate serial: %w", err)
+		}
+		cert.Serial = binary.BigEndian.Uint64(buf[:])
+	}
+	if opts.Host {
+		if len(opts.Options) > 0 {
+			return nil, fmt.Errorf("host certificates do not take options")
+		}
+		cert.CertType = ssh.HostCert
+	} else {
+		for _, name := range defaultSSHCertExtensions {
+			cert.Permissions.Extensions[name] = ""
+		}
+		if err := applySSHCertOptions(&cert.Permissions, opts.Options); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	if forever {
+		cert.ValidAfter, cert.ValidBefore = 0, ssh.CertTimeInfinity
+	} else {
+		cert.ValidAfter = uint64(now.Add(-5 * time.Minute).Unix())
+		cert.ValidBefore = uint64(now.Add(validity).Unix())
+	}
+	if err := cert.SignCert(rand.Reader, caSigner); err != nil {
+		return nil, fmt.Errorf("failed to sign certificate: %w", err)
+	}
+
+	outputPath := opts.OutputPath
+	if outputPath == "" {
+		outputPath = sshCertOutputPath(publicKeyPath)
+	}
+	if err := os.WriteFile(outputPath, marshalAuthorizedKey(cert, comment), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write certificate: %w", err)
+	}
+
+	result := &SSHCertResult{
+		CertPath:      outputPath,
+		Type:          "user",
+		KeyID:         cert.KeyId,
+		Serial:        cert.Serial,
+		Principals:    cert.ValidPrincipals,
+		Forever:       forever,
+		ValidAfter:    time.Unix(int64(cert.ValidAfter), 0),
+		ValidBefore:   time.Unix(int64(cert.ValidBefore), 0),
+		CAFingerprint: ssh.FingerprintSHA256(caSigner.PublicKey()),
+	}
+	if opts.Host {
+		result.Type = "host"
+	}
+	for name, value := range cert.Extensions {
+		result.Extensions = append(result.Extensions, sshOptionString(name, value))
+	}
+	for name, value := range cert.CriticalOptions {
+		result.Critical = append(result.Critical, sshOptionString(name, value))
+	}
+	slices.Sort(result.Extensions)
+	slices.Sort(result.Critical)
+	return result, nil
+}
+
+func sshOptionString(name, value string) string {
+	if value == "" {
+		return name
+	}
+	return name + "=" + value
+}
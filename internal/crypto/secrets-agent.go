@@ -0,0 +1,125 @@
+package anbuCrypto
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	agentRequestKey  = "KEY"
+	agentRequestLock = "LOCK"
+	agentDialTimeout = time.Second
+)
+
+func SecretsAgentSocket(storePath string) (string, error) {
+	absPath, err := filepath.Abs(storePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve store path: %w", err)
+	}
+	agentDir := filepath.Join(filepath.Dir(absPath), "agent")
+	if err := os.MkdirAll(agentDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create agent directory: %w", err)
+	}
+	sum := sha256.Sum256([]byte(absPath))
+	return filepath.Join(agentDir, hex.EncodeToString(sum[:8])+".sock"), nil
+}
+
+func RunSecretsAgent(socketPath string, key []byte, ttl time.Duration) error {
+	if conn, err := net.DialTimeout("unix", socketPath, agentDialTimeout); err == nil {
+		conn.Close()
+		return fmt.Errorf("an agent is already listening on %s", socketPath)
+	}
+	os.Remove(socketPath)
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on agent socket: %w", err)
+	}
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict agent socket: %w", err)
+	}
+	// the timer only stops the accept loop, the key is cleared once the loop
+	// (its only reader) has returned
+	defer func() {
+		clear(key)
+		os.Remove(socketPath)
+	}()
+	defer listener.Close()
+	timer := time.AfterFunc(ttl, func() { listener.Close() })
+	defer timer.Stop()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return nil
+		}
+		if handleAgentConn(conn, key) {
+			return nil
+		}
+	}
+}
+
+func handleAgentConn(conn net.Conn, key []byte) bool {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	request, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	switch strings.TrimSpace(request) {
+	case agentRequestKey:
+		fmt.Fprintln(conn, base64.StdEncoding.EncodeToString(key))
+	case agentRequestLock:
+		fmt.Fprintln(conn, "OK")
+		return true
+	default:
+		fmt.Fprintln(conn, "ERR unknown request")
+	}
+	return false
+}
+
+func agentRequest(socketPath, request string) (string, error) {
+	conn, err := net.DialTimeout("unix", socketPath, agentDialTimeout)
+	if err != nil {
+		return "", fmt.Errorf("no agent running: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := fmt.Fprintln(conn, request); err != nil {
+		return "", fmt.Errorf("failed to send agent request: %w", err)
+	}
+	response, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read agent response: %w", err)
+	}
+	response = strings.TrimSpace(response)
+	if strings.HasPrefix(response, "ERR") {
+		return "", fmt.Errorf("agent error: %s", response)
+	}
+	return response, nil
+}
+
+func RequestAgentKey(socketPath string) ([]byte, error) {
+	response, err := agentRequest(socketPath, agentRequestKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(response)
+	if err != nil || len(key) != secretsKeyLength {
+		return nil, fmt.Errorf("agent returned an invalid key")
+	}
+	return key, nil
+}
+
+func LockSecretsAgent(socketPath string) error {
+	_, err := agentRequest(socketPath, agentRequestLock)
+	return err
+}
@@ -0,0 +1,385 @@
+package anbuCrypto
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	SecretKindFile = "file"
+
+	attachmentMagic      = "anbu-blob/v1\n"
+	attachmentRefPrefix  = "anbu-attachment:"
+	attachmentChunkSize  = 64 * 1024
+	attachmentPrefixSize = 7
+)
+
+type AttachmentInfo struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// the encrypted secret value holds the blob ID and its random file key, so
+// rekeying the store re-wraps the file key without touching the blob
+type attachmentRef struct {
+	AttachmentInfo
+	Blob string `json:"blob"`
+	Key  string `json:"key"`
+}
+
+func attachmentsDir(filePath string) string {
+	base := filepath.Base(filePath)
+	return filepath.Join(filepath.Dir(filePath), "attachments", strings.TrimSuffix(base, filepath.Ext(base)))
+}
+
+func attachmentBlobPath(filePath, blob string) string {
+	return filepath.Join(attachmentsDir(filePath), blob+".blob")
+}
+
+func parseAttachmentRef(value string) (*attachmentRef, bool) {
+	data, found := strings.CutPrefix(value, attachmentRefPrefix)
+	if !found {
+		return nil, false
+	}
+	var ref attachmentRef
+	if err := json.Unmarshal([]byte(data), &ref); err != nil || ref.Blob == "" {
+		return nil, false
+	}
+	return &ref, true
+}
+
+func newAttachmentGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// STREAM construction: every chunk is sealed with nonce = prefix || counter ||
+// last-flag and the header as additional data, so reordering, dropping or
+// truncating chunks fails authentication
+func encryptAttachment(dst io.Writer, src io.Reader, key []byte) (int64, hash.Hash, error) {
+	gcm, err := newAttachmentGCM(key)
+	if err != nil {
+		return 0, nil, err
+	}
+	header := make([]byte, len(attachmentMagic)+attachmentPrefixSize)
+	copy(header, attachmentMagic)
+	if _, err := io.ReadFull(rand.Reader, header[len(attachmentMagic):]); err != nil {
+		return 0, nil, fmt.Errorf("failed to generate nonce prefix: %w", err)
+	}
+	if _, err := dst.Write(header); err != nil {
+		return 0, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	copy(nonce, header[len(attachmentMagic):])
+	reader := bufio.NewReaderSize(src, attachmentChunkSize)
+	buf := make([]byte, attachmentChunkSize)
+	digest := sha256.New()
+	var size int64
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(reader, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		last := n < attachmentChunkSize
+		if !last {
+			if _, err := reader.Peek(1); err == io.EOF {
+				last = true
+			} else if err != nil {
+				return 0, nil, fmt.Errorf("failed to read file: %w", err)
+			}
+		}
+		if !last && counter == math.MaxUint32 {
+			return 0, nil, fmt.Errorf("file too large")
+		}
+		digest.Write(buf[:n])
+		size += int64(n)
+		setChunkNonce(nonce, counter, last)
+		if _, err := dst.Write(gcm.Seal(nil, nonce, buf[:n], header)); err != nil {
+			return 0, nil, err
+		}
+		if last {
+			return size, digest, nil
+		}
+	}
+}
+
+func decryptAttachment(dst io.Writer, src io.Reader, key []byte) (int64, hash.Hash, error) {
+	gcm, err := newAttachmentGCM(key)
+	if err != nil {
+		return 0, nil, err
+	}
+	reader := bufio.NewReaderSize(src, attachmentChunkSize+gcm.Overhead())
+	header := make([]byte, len(attachmentMagic)+attachmentPrefixSize)
+	if _, err := io.ReadFull(reader, header); err != nil || string(header[:len(attachmentMagic)]) != attachmentMagic {
+		return 0, nil, fmt.Errorf("not an anbu attachment blob")
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	copy(nonce, header[len(attachmentMagic):])
+	buf := make([]byte, attachmentChunkSize+gcm.Overhead())
+	digest := sha256.New()
+	var size int64
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(reader, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, nil, fmt.Errorf("failed to read attachment: %w", err)
+		}
+		last := n < len(buf)
+		if !last {
+			if _, err := reader.Peek(1); err == io.EOF {
+				last = true
+			}
+		}
+		setChunkNonce(nonce, counter, last)
+		plaintext, err := gcm.Open(buf[:0], nonce, buf[:n], header)
+		if err != nil {
+			return 0, nil, fmt.Errorf("attachment is corrupted or truncated")
+		}
+		if _, err := dst.Write(plaintext); err != nil {
+			return 0, nil, err
+		}
+		digest.Write(plaintext)
+		size += int64(len(plaintext))
+		if last {
+			return size, digest, nil
+		}
+	}
+}
+
+func setChunkNonce(nonce []byte, counter uint32, last bool) {
+	binary.BigEndian.PutUint32(nonce[attachmentPrefixSize:], counter)
+	nonce[len(nonce)-1] = 0
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+}
+
+func AttachFile(filePath, secretID, srcPath string, meta *SecretMetadata, key []byte) (*AttachmentInfo, error) {
+	unlock, err := lockSecretsStore(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	store, err := loadSecretsStore(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.verifyKey(key); err != nil {
+		return nil, err
+	}
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer src.Close()
+
+	blobID := make([]byte, 16)
+	fileKey := make([]byte, secretsKeyLength)
+	for _, buf := range [][]byte{blobID, fileKey} {
+		if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+			return nil, fmt.Errorf("failed to generate attachment key: %w", err)
+		}
+	}
+	ref := attachmentRef{
+		AttachmentInfo: AttachmentInfo{Name: filepath.Base(srcPath)},
+		Blob:           hex.EncodeToString(blobID),
+		Key:            base64.StdEncoding.EncodeToString(fileKey),
+	}
+	blobPath := attachmentBlobPath(filePath, ref.Blob)
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create attachments directory: %w", err)
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(blobPath), ".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blob: %w", err)
+	}
+	size, digest, err := encryptAttachment(tmpFile, src, fileKey)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	tmpFile.Close()
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), blobPath)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return nil, fmt.Errorf("failed to write blob: %w", err)
+	}
+	ref.Size = size
+	ref.SHA256 = hex.EncodeToString(digest.Sum(nil))
+
+	refData, err := json.Marshal(ref)
+	if err != nil {
+		os.Remove(blobPath)
+		return nil, fmt.Errorf("failed to marshal attachment: %w", err)
+	}
+	encryptedValue, err := encryptString(attachmentRefPrefix+string(refData), key)
+	if err != nil {
+		os.Remove(blobPath)
+		return nil, fmt.Errorf("failed to encrypt attachment key: %w", err)
+	}
+	if meta == nil {
+		meta = &SecretMetadata{}
+	}
+	meta.Kind = SecretKindFile
+	store.setEntry(secretID, encryptedValue, meta, time.Now())
+	if err := saveSecretsStore(store, filePath, key); err != nil {
+		os.Remove(blobPath)
+		return nil, err
+	}
+	pruneAttachments(store, filePath, key)
+	return &ref.AttachmentInfo, nil
+}
+
+// ExtractFile decrypts an attachment to outPath ("-" for stdout); files are
+// only put in place after the size and digest have been verified
+func ExtractFile(filePath, secretID, outPath string, key []byte) (*AttachmentInfo, error) {
+	store, err := loadSecretsStore(filePath)
+	if err != nil {
+		return nil, err
+	}
+	entry, exists := store.Secrets[secretID]
+	if !exists {
+		return nil, fmt.Errorf("secret '%s' not found", secretID)
+	}
+	if err := store.verifyKey(key); err != nil {
+		return nil, err
+	}
+	value, err := decryptString(entry.Value, key)
+	if err != nil {
+		return nil, err
+	}
+	ref, ok := parseAttachmentRef(value)
+	if !ok {
+		return nil, fmt.Errorf("secret '%s' is not a file attachment", secretID)
+	}
+	fileKey, err := base64.StdEncoding.DecodeString(ref.Key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attachment key: %w", err)
+	}
+	blob, err := os.Open(attachmentBlobPath(filePath, ref.Blob))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open attachment blob: %w", err)
+	}
+	defer blob.Close()
+	verify := func(size int64, digest hash.Hash) error {
+		if size != ref.Size || hex.EncodeToString(digest.Sum(nil)) != ref.SHA256 {
+			return fmt.Errorf("attachment does not match its recorded size and digest")
+		}
+		return nil
+	}
+	if outPath == "-" {
+		size, digest, err := decryptAttachment(os.Stdout, blob, fileKey)
+		if err != nil {
+			return nil, err
+		}
+		return &ref.AttachmentInfo, verify(size, digest)
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(outPath), "."+filepath.Base(outPath)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	size, digest, err := decryptAttachment(tmpFile, blob, fileKey)
+	if err == nil {
+		err = verify(size, digest)
+	}
+	if err == nil {
+		err = tmpFile.Chmod(0600)
+	}
+	tmpFile.Close()
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), outPath)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return nil, err
+	}
+	return &ref.AttachmentInfo, nil
+}
+
+// pruneAttachments removes blobs no longer referenced by any current or
+// historical value; callers must hold the store lock
+func pruneAttachments(store *SecretsStore, filePath string, key []byte) {
+	files, err := os.ReadDir(attachmentsDir(filePath))
+	if err != nil {
+		return
+	}
+	referenced := make(map[string]bool)
+	for _, entry := range store.Secrets {
+		values := []string{entry.Value}
+		for _, version := range entry.History {
+			values = append(values, version.Value)
+		}
+		for _, encrypted := range values {
+			value, err := decryptString(encrypted, key)
+			if err != nil {
+				// an unreadable value might reference any blob, keep them all
+				return
+			}
+			if ref, ok := parseAttachmentRef(value); ok {
+				referenced[ref.Blob] = true
+			}
+		}
+	}
+	for _, file := range files {
+		blob, isBlob := strings.CutSuffix(file.Name(), ".blob")
+		if isBlob && !referenced[blob] {
+			os.Remove(filepath.Join(attachmentsDir(filePath), file.Name()))
+		}
+	}
+}
+
+func copyAttachmentBlob(srcPath, dstPath, encryptedValue string, key []byte) error {
+	value, err := decryptString(encryptedValue, key)
+	if err != nil {
+		return err
+	}
+	ref, ok := parseAttachmentRef(value)
+	if !ok {
+		return nil
+	}
+	src, err := os.Open(attachmentBlobPath(srcPath, ref.Blob))
+	if err != nil {
+		return fmt.Errorf("failed to open attachment blob: %w", err)
+	}
+	defer src.Close()
+	dstBlob := attachmentBlobPath(dstPath, ref.Blob)
+	if err := os.MkdirAll(filepath.Dir(dstBlob), 0700); err != nil {
+		return fmt.Errorf("failed to create attachments directory: %w", err)
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(dstBlob), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob: %w", err)
+	}
+	_, err = io.Copy(tmpFile, src)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	tmpFile.Close()
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), dstBlob)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return fmt.Errorf("failed to copy attachment blob: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+package anbuCrypto
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	anbuGenerics "github.com/tanq16/anbu/internal/generics"
+)
+
+const DefaultAuditMinEntropy = 60
+
+type AuditFinding struct {
+	ID      string
+	Entropy float64
+	Issues  []string
+}
+
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+	"abcdefghijklmnopqrstuvwxyz",
+	"0123456789",
+}
+
+var leetReplacer = strings.NewReplacer("0", "o", "1", "l", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s")
+
+type auditDictionary struct {
+	words   map[string]bool
+	maxLen  int
+	entropy float64
+}
+
+func newAuditDictionary() *auditDictionary {
+	dict := &auditDictionary{words: make(map[string]bool)}
+	for _, word := range anbuGenerics.PassphraseWords() {
+		if len(word) < 4 {
+			continue
+		}
+		dict.words[word] = true
+		dict.maxLen = max(dict.maxLen, len(word))
+	}
+	dict.entropy = math.Log2(float64(len(dict.words)))
+	return dict
+}
+
+// AuditSecrets decrypts every value and returns the entries that look weak,
+// are reused, or appear in a local HIBP dump; the second value is the number
+// of secrets audited
+func AuditSecrets(filePath string, key []byte, minEntropy float64, hibpPath string) ([]AuditFinding, int, error) {
+	// attachments are files rather than passwords, so they are not audited
+	records, _, err := ExportSecretRecords(filePath, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	dict := newAuditDictionary()
+	byValue := make(map[string][]string)
+	findings := make(map[string]*AuditFinding)
+	for _, record := range records {
+		byValue[record.Value] = append(byValue[record.Value], record.ID)
+		finding := &AuditFinding{ID: record.ID}
+		findings[record.ID] = finding
+		if record.Kind == SecretKindTOTP {
+			finding.Entropy = math.Inf(1)
+			continue
+		}
+		var patterns []string
+		finding.Entropy, patterns = estimateEntropy(record.Value, dict)
+		if finding.Entropy < minEntropy {
+			finding.Issues = append(finding.Issues, fmt.Sprintf("low entropy (~%.0f bits)", finding.Entropy))
+			finding.Issues = append(finding.Issues, patterns...)
+		}
+	}
+	for _, ids := range byValue {
+		if len(ids) < 2 {
+			continue
+		}
+		for _, id := range ids {
+			var others []string
+			for _, other := range ids {
+				if other != id {
+					others = append(others, other)
+				}
+			}
+			findings[id].Issues = append(findings[id].Issues, "reused by "+strings.Join(others, ", "))
+		}
+	}
+	if hibpPath != "" {
+		counts, err := lookupHIBP(hibpPath, records)
+		if err != nil {
+			return nil, 0, err
+		}
+		for id, count := range counts {
+			findings[id].Issues = append(findings[id].Issues, fmt.Sprintf("found in breach data (%d times)", count))
+		}
+	}
+	var risky []AuditFinding
+	for _, record := range records {
+		if finding := findings[record.ID]; len(finding.Issues) > 0 {
+			risky = append(risky, *finding)
+		}
+	}
+	sort.Slice(risky, func(i, j int) bool {
+		return risky[i].Entropy < risky[j].Entropy
+	})
+	return risky, len(records), nil
+}
+
+func charsetSize(value string) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	size := 0
+	for _, set := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if set.present {
+			size += set.size
+		}
+	}
+	return max(size, 1)
+}
+
+// estimateEntropy charges brute-force bits per character, except for
+// dictionary words, keyboard walks and repeated characters which are
+// charged as a single guess from their much smaller space
+func estimateEntropy(value string, dict *auditDictionary) (float64, []string) {
+	runes := []rune(value)
+	lower := []rune(strings.ToLower(value))
+	normalized := []rune(leetReplacer.Replace(strings.ToLower(value)))
+	perChar := math.Log2(float64(charsetSize(value)))
+	var patterns []string
+	entropy := 0.0
+	for i := 0; i < len(runes); {
+		if word := dict.longestWordAt(normalized, i); word != "" {
+			patterns = append(patterns, fmt.Sprintf("dictionary word '%s'", string(runes[i:i+len([]rune(word))])))
+			entropy += dict.entropy
+			i += len([]rune(word))
+			continue
+		}
+		if n := keyboardWalkAt(lower, i); n >= 4 {
+			patterns = append(patterns, fmt.Sprintf("keyboard walk '%s'", string(runes[i:i+n])))
+			entropy += math.Log2(float64(len(keyboardRows)*26*2)) + math.Log2(float64(n))
+			i += n
+			continue
+		}
+		if n := repeatAt(runes, i); n >= 3 {
+			patterns = append(patterns, fmt.Sprintf("repeated '%c'", runes[i]))
+			entropy += perChar + math.Log2(float64(n))
+			i += n
+			continue
+		}
+		entropy += perChar
+		i++
+	}
+	return entropy, patterns
+}
+
+func (d *auditDictionary) longestWordAt(runes []rune, start int) string {
+	for length := min(d.maxLen, len(runes)-start); length >= 4; length-- {
+		if candidate := string(runes[start : start+length]); d.words[candidate] {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func keyboardWalkAt(runes []rune, start int) int {
+	best := 1
+	for _, row := range keyboardRows {
+		pos := strings.IndexRune(row, runes[start])
+		if pos < 0 {
+			continue
+		}
+		for _, step := range []int{1, -1} {
+			n := 1
+			for next := pos + step; start+n < len(runes) && next >= 0 && next < len(row) && rune(row[next]) == runes[start+n]; next += step {
+				n++
+			}
+			best = max(best, n)
+		}
+	}
+	return best
+}
+
+func repeatAt(runes []rune, start int) int {
+	n := 1
+	for start+n < len(runes) && runes[start+n] == runes[start] {
+		n++
+	}
+	return n
+}
+
+// lookupHIBP accepts either a directory of range files named by their 5
+// character SHA-1 prefix (SUFFIX:COUNT lines), a single such range file, or a
+// single file of full HASH:COUNT lines as distributed in the ordered-by-hash
+// download
+func lookupHIBP(hibpPath string, records []SecretRecord) (map[string]int, error) {
+	info, err := os.Stat(hibpPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open HIBP data: %w", err)
+	}
+	hashes := make(map[string][]string)
+	for _, record := range records {
+		sum := sha1.Sum([]byte(record.Value))
+		hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+		hashes[hash] = append(hashes[hash], record.ID)
+	}
+	counts := make(map[string]int)
+	if !info.IsDir() {
+		err := scanHIBPFile(hibpPath, hibpRangePrefix(hibpPath), hashes, counts)
+		return counts, err
+	}
+	prefixes := make(map[string]bool)
+	for hash := range hashes {
+		prefixes[hash[:5]] = true
+	}
+	for prefix := range prefixes {
+		for _, name := range []string{prefix, prefix + ".txt", strings.ToLower(prefix), strings.ToLower(prefix) + ".txt"} {
+			rangePath := filepath.Join(hibpPath, name)
+			if _, err := os.Stat(rangePath); err == nil {
+				if err := scanHIBPFile(rangePath, prefix, hashes, counts); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+	}
+	return counts, nil
+}
+
+// hibpRangePrefix returns the SHA-1 prefix of a range file named like 5BAA6 or
+// 5baa6.txt, or "" for any other name
+func hibpRangePrefix(path string) string {
+	name := filepath.Base(path)
+	if ext := filepath.Ext(name); strings.EqualFold(ext, ".txt") {
+		name = strings.TrimSuffix(name, ext)
+	}
+	name = strings.ToUpper(name)
+	if len(name) != 5 || strings.Trim(name, "0123456789ABCDEF") != "" {
+		return ""
+	}
+	return name
+}
+
+func scanHIBPFile(path, prefix string, hashes map[string][]string, counts map[string]int) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open HIBP file: %w", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		hash, count, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !found {
+			continue
+		}
+		// a range file read without its prefix would never match anything
+		if _, err := hex.DecodeString(hash); prefix == "" && (len(hash) != 2*sha1.Size || err != nil) {
+			return fmt.Errorf("%s line %d is not a full SHA-1 hash, name range files by their 5 character prefix", path, line)
+		}
+		ids, exists := hashes[prefix+strings.ToUpper(hash)]
+		if !exists {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil {
+			n = 1
+		}
+		for _, id := range ids {
+			counts[id] = n
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read HIBP file: %w", err)
+	}
+	return nil
+}
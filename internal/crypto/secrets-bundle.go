@@ -0,0 +1,126 @@
+package anbuCrypto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	secretsBundleMagic   = "anbu-bundle"
+	secretsBundleVersion = 1
+)
+
+type SecretsBundleHeader struct {
+	Version   int         `json:"version"`
+	Entries   int         `json:"entries"`
+	CreatedAt time.Time   `json:"created_at"`
+	KDF       *SecretsKDF `json:"kdf"`
+}
+
+// the header line is bound to the payload as AES-GCM additional data, so
+// editing the entry count, version or KDF parameters breaks decryption
+func ExportSecretsBundle(filePath, bundlePath, passphrase string, key []byte) (int, []FormatIssue, error) {
+	records, skipped, err := ExportSecretRecords(filePath, key)
+	if err != nil {
+		return 0, nil, err
+	}
+	kdf, err := NewSecretsKDF(DefaultSecretsKDF)
+	if err != nil {
+		return 0, nil, err
+	}
+	bundleKey, err := kdf.deriveKey(passphrase)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to derive bundle key: %w", err)
+	}
+	header := SecretsBundleHeader{
+		Version:   secretsBundleVersion,
+		Entries:   len(records),
+		CreatedAt: time.Now().UTC(),
+		KDF:       kdf,
+	}
+	headerData, err := json.Marshal(header)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to marshal bundle header: %w", err)
+	}
+	payload, err := json.Marshal(records)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to marshal bundle payload: %w", err)
+	}
+	ciphertext, err := sealWithAAD(payload, bundleKey, headerData)
+	if err != nil {
+		return 0, nil, err
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s/v%d\n", secretsBundleMagic, secretsBundleVersion)
+	out.Write(headerData)
+	out.WriteByte('\n')
+	out.WriteString(base64.StdEncoding.EncodeToString(ciphertext))
+	out.WriteByte('\n')
+	if err := os.WriteFile(bundlePath, out.Bytes(), 0600); err != nil {
+		return 0, nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return len(records), skipped, nil
+}
+
+func IsSecretsBundle(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	prefix := make([]byte, len(secretsBundleMagic))
+	if _, err := io.ReadFull(file, prefix); err != nil {
+		return false
+	}
+	return string(prefix) == secretsBundleMagic
+}
+
+func ReadSecretsBundle(bundlePath, passphrase string) ([]SecretRecord, *SecretsBundleHeader, error) {
+	data, err := os.ReadFile(bundlePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	var lines [][]byte
+	for scanner.Scan() {
+		lines = append(lines, bytes.Clone(scanner.Bytes()))
+	}
+	if len(lines) != 3 || string(lines[0]) != fmt.Sprintf("%s/v%d", secretsBundleMagic, secretsBundleVersion) {
+		return nil, nil, fmt.Errorf("not a supported anbu bundle")
+	}
+	headerData := lines[1]
+	var header SecretsBundleHeader
+	if err := json.Unmarshal(headerData, &header); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse bundle header: %w", err)
+	}
+	if header.Version != secretsBundleVersion || header.KDF == nil {
+		return nil, nil, fmt.Errorf("unsupported bundle header")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(string(lines[2]))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode bundle payload: %w", err)
+	}
+	bundleKey, err := header.KDF.deriveKey(passphrase)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to derive bundle key: %w", err)
+	}
+	payload, err := openWithAAD(ciphertext, bundleKey, headerData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("wrong passphrase or tampered bundle")
+	}
+	var records []SecretRecord
+	if err := json.Unmarshal(payload, &records); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse bundle payload: %w", err)
+	}
+	if len(records) != header.Entries {
+		return nil, nil, fmt.Errorf("bundle holds %d entries but header lists %d", len(records), header.Entries)
+	}
+	return records, &header, nil
+}
@@ -0,0 +1,229 @@
+package anbuCrypto
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type bitwardenExport struct {
+	Encrypted bool              `json:"encrypted"`
+	Folders   []bitwardenFolder `json:"folders"`
+	Items     []bitwardenItem   `json:"items"`
+}
+
+type bitwardenFolder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type bitwardenItem struct {
+	ID       string           `json:"id,omitempty"`
+	FolderID string           `json:"folderId,omitempty"`
+	Type     int              `json:"type"`
+	Name     string           `json:"name"`
+	Notes    string           `json:"notes,omitempty"`
+	Login    *bitwardenLogin  `json:"login,omitempty"`
+	Fields   []bitwardenField `json:"fields,omitempty"`
+}
+
+type bitwardenLogin struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	TOTP     string `json:"totp,omitempty"`
+}
+
+type bitwardenField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Type  int    `json:"type"`
+}
+
+const (
+	bitwardenTypeLogin      = 1
+	bitwardenTypeSecureNote = 2
+)
+
+func parseBitwardenSecrets(data []byte) ([]SecretRecord, []FormatIssue, error) {
+	var export bitwardenExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse Bitwarden export: %w", err)
+	}
+	if export.Encrypted {
+		return nil, nil, fmt.Errorf("encrypted Bitwarden exports are not supported, export as unencrypted JSON")
+	}
+	folders := make(map[string]string)
+	for _, folder := range export.Folders {
+		folders[folder.ID] = folder.Name
+	}
+	collector := newRecordCollector()
+	for i, item := range export.Items {
+		row := i + 1
+		var tags []string
+		if folder := folders[item.FolderID]; folder != "" {
+			tags = []string{folder}
+		}
+		switch {
+		case item.Type == bitwardenTypeLogin && item.Login != nil:
+			collector.add(row, SecretRecord{ID: item.Name, Value: item.Login.Password, Tags: tags, Note: item.Notes})
+			if item.Login.Username != "" {
+				collector.add(row, SecretRecord{ID: item.Name + ".username", Value: item.Login.Username, Tags: tags})
+			}
+			if item.Login.TOTP != "" {
+				collector.add(row, SecretRecord{ID: item.Name + ".totp", Value: item.Login.TOTP, Tags: tags})
+			}
+		case item.Type == bitwardenTypeSecureNote:
+			collector.add(row, SecretRecord{ID: item.Name, Value: item.Notes, Tags: tags})
+		default:
+			collector.skip(row, item.Name, fmt.Sprintf("unsupported item type %d", item.Type))
+			continue
+		}
+		for _, field := range item.Fields {
+			collector.add(row, SecretRecord{ID: item.Name + "." + field.Name, Value: field.Value, Tags: tags})
+		}
+	}
+	return collector.result()
+}
+
+func renderBitwardenSecrets(records []SecretRecord) ([]byte, []FormatIssue, error) {
+	export := bitwardenExport{Folders: []bitwardenFolder{}, Items: []bitwardenItem{}}
+	folderIDs := make(map[string]string)
+	for _, record := range records {
+		item := bitwardenItem{
+			ID:    uuid.New().String(),
+			Type:  bitwardenTypeLogin,
+			Name:  record.ID,
+			Notes: record.Note,
+			Login: &bitwardenLogin{Password: record.Value},
+		}
+		if len(record.Tags) > 0 {
+			folder := record.Tags[0]
+			if _, exists := folderIDs[folder]; !exists {
+				folderIDs[folder] = uuid.New().String()
+				export.Folders = append(export.Folders, bitwardenFolder{ID: folderIDs[folder], Name: folder})
+			}
+			item.FolderID = folderIDs[folder]
+		}
+		export.Items = append(export.Items, item)
+	}
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal Bitwarden export: %w", err)
+	}
+	return data, nil, nil
+}
+
+type keePassFile struct {
+	XMLName xml.Name    `xml:"KeePassFile"`
+	Root    keePassRoot `xml:"Root"`
+}
+
+type keePassRoot struct {
+	Groups []keePassGroup `xml:"Group"`
+}
+
+type keePassGroup struct {
+	Name    string         `xml:"Name"`
+	Entries []keePassEntry `xml:"Entry"`
+	Groups  []keePassGroup `xml:"Group"`
+}
+
+type keePassEntry struct {
+	Strings []keePassString `xml:"String"`
+}
+
+type keePassString struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+func (e keePassEntry) field(key string) string {
+	for _, s := range e.Strings {
+		if strings.EqualFold(s.Key, key) {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+var keePassStandardFields = []string{"Title", "Password", "UserName", "Notes", "URL", "otp"}
+
+func parseKeePassSecrets(data []byte) ([]SecretRecord, []FormatIssue, error) {
+	var file keePassFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse KeePass XML: %w", err)
+	}
+	collector := newRecordCollector()
+	row := 0
+	var walk func(group keePassGroup, path []string)
+	walk = func(group keePassGroup, path []string) {
+		var tags []string
+		if len(path) > 0 {
+			tags = []string{strings.Join(path, "/")}
+		}
+		for _, entry := range group.Entries {
+			row++
+			title := entry.field("Title")
+			collector.add(row, SecretRecord{ID: title, Value: entry.field("Password"), Tags: tags, Note: entry.field("Notes")})
+			if username := entry.field("UserName"); username != "" && title != "" {
+				collector.add(row, SecretRecord{ID: title + ".username", Value: username, Tags: tags})
+			}
+			if totp := entry.field("otp"); totp != "" && title != "" {
+				collector.add(row, SecretRecord{ID: title + ".totp", Value: totp, Tags: tags})
+			}
+			for _, s := range entry.Strings {
+				if !isKeePassStandardField(s.Key) && title != "" {
+					collector.add(row, SecretRecord{ID: title + "." + s.Key, Value: s.Value, Tags: tags})
+				}
+			}
+		}
+		for _, child := range group.Groups {
+			walk(child, append(append([]string{}, path...), child.Name))
+		}
+	}
+	// the top-level group is the database root and is not used as a tag
+	for _, group := range file.Root.Groups {
+		walk(group, nil)
+	}
+	return collector.result()
+}
+
+func isKeePassStandardField(key string) bool {
+	for _, field := range keePassStandardFields {
+		if strings.EqualFold(field, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func renderKeePassSecrets(records []SecretRecord) ([]byte, []FormatIssue, error) {
+	root := keePassGroup{Name: "anbu"}
+	groups := make(map[string]int)
+	for _, record := range records {
+		entry := keePassEntry{Strings: []keePassString{
+			{Key: "Title", Value: record.ID},
+			{Key: "Password", Value: record.Value},
+			{Key: "Notes", Value: record.Note},
+		}}
+		if len(record.Tags) == 0 {
+			root.Entries = append(root.Entries, entry)
+			continue
+		}
+		idx, exists := groups[record.Tags[0]]
+		if !exists {
+			idx = len(root.Groups)
+			groups[record.Tags[0]] = idx
+			root.Groups = append(root.Groups, keePassGroup{Name: record.Tags[0]})
+		}
+		root.Groups[idx].Entries = append(root.Groups[idx].Entries, entry)
+	}
+	data, err := xml.MarshalIndent(keePassFile{Root: keePassRoot{Groups: []keePassGroup{root}}}, "", "\t")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal KeePass XML: %w", err)
+	}
+	return append([]byte(xml.Header), data...), nil, nil
+}
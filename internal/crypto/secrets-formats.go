@@ -0,0 +1,290 @@
+package anbuCrypto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const DefaultSecretsFormat = "json"
+
+type FormatIssue struct {
+	Row    int
+	ID     string
+	Reason string
+}
+
+type secretsFormat struct {
+	parse  func(data []byte) ([]SecretRecord, []FormatIssue, error)
+	render func(records []SecretRecord) ([]byte, []FormatIssue, error)
+}
+
+var secretsFormats = map[string]secretsFormat{
+	"json":           {parse: parseJSONSecrets, render: renderJSONSecrets},
+	"env":            {parse: parseEnvSecrets, render: renderEnvSecrets},
+	"csv":            {parse: parseCSVSecrets, render: renderCSVSecrets},
+	"bitwarden-json": {parse: parseBitwardenSecrets, render: renderBitwardenSecrets},
+	"keepass-xml":    {parse: parseKeePassSecrets, render: renderKeePassSecrets},
+}
+
+func SupportedSecretsFormats() []string {
+	var names []string
+	for name := range secretsFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func ReadSecretsFile(importFilePath, format string) ([]SecretRecord, []FormatIssue, error) {
+	impl, ok := secretsFormats[format]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+	data, err := os.ReadFile(importFilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read import file: %w", err)
+	}
+	return impl.parse(data)
+}
+
+func WriteSecretsFile(exportFilePath, format string, records []SecretRecord) ([]FormatIssue, error) {
+	impl, ok := secretsFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+	data, issues, err := impl.render(records)
+	if err != nil {
+		return nil, err
+	}
+	return issues, os.WriteFile(exportFilePath, data, 0600)
+}
+
+// recordCollector drops empty and duplicate rows while remembering why
+type recordCollector struct {
+	records []SecretRecord
+	issues  []FormatIssue
+	seen    map[string]int
+}
+
+func newRecordCollector() *recordCollector {
+	return &recordCollector{seen: make(map[string]int)}
+}
+
+func (c *recordCollector) add(row int, record SecretRecord) {
+	record.ID = strings.TrimSpace(record.ID)
+	switch {
+	case record.ID == "":
+		c.skip(row, "", "missing name")
+	case record.Value == "":
+		c.skip(row, record.ID, "empty value")
+	default:
+		if firstRow, exists := c.seen[record.ID]; exists {
+			c.skip(row, record.ID, fmt.Sprintf("duplicate of row %d", firstRow))
+			return
+		}
+		c.seen[record.ID] = row
+		c.records = append(c.records, record)
+	}
+}
+
+func (c *recordCollector) skip(row int, id, reason string) {
+	c.issues = append(c.issues, FormatIssue{Row: row, ID: id, Reason: reason})
+}
+
+func (c *recordCollector) result() ([]SecretRecord, []FormatIssue, error) {
+	return c.records, c.issues, nil
+}
+
+func parseJSONSecrets(data []byte) ([]SecretRecord, []FormatIssue, error) {
+	var importStore secretsExport
+	if err := json.Unmarshal(data, &importStore); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse import file: %w", err)
+	}
+	var ids []string
+	for id := range importStore.Secrets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	collector := newRecordCollector()
+	for i, id := range ids {
+		collector.add(i+1, SecretRecord{ID: id, Value: importStore.Secrets[id]})
+	}
+	return collector.result()
+}
+
+func renderJSONSecrets(records []SecretRecord) ([]byte, []FormatIssue, error) {
+	exportStore := &secretsExport{
+		Secrets: make(map[string]string),
+	}
+	for _, record := range records {
+		exportStore.Secrets[record.ID] = record.Value
+	}
+	data, err := json.MarshalIndent(exportStore, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal export data: %w", err)
+	}
+	return data, nil, nil
+}
+
+var envNameInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+func parseEnvSecrets(data []byte) ([]SecretRecord, []FormatIssue, error) {
+	collector := newRecordCollector()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	row := 0
+	for scanner.Scan() {
+		row++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, value, found := strings.Cut(line, "=")
+		if !found {
+			collector.skip(row, "", "not a KEY=VALUE line")
+			continue
+		}
+		value, err := unquoteEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			collector.skip(row, strings.TrimSpace(name), err.Error())
+			continue
+		}
+		collector.add(row, SecretRecord{ID: name, Value: value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	return collector.result()
+}
+
+func unquoteEnvValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid double-quoted value")
+		}
+		return unquoted, nil
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", fmt.Errorf("invalid single-quoted value")
+		}
+		return value[1 : len(value)-1], nil
+	default:
+		if idx := strings.Index(value, " #"); idx >= 0 {
+			value = strings.TrimSpace(value[:idx])
+		}
+		return value, nil
+	}
+}
+
+func renderEnvSecrets(records []SecretRecord) ([]byte, []FormatIssue, error) {
+	var out bytes.Buffer
+	var issues []FormatIssue
+	seen := make(map[string]string)
+	for i, record := range records {
+		name := strings.ToUpper(envNameInvalidChars.ReplaceAllString(record.ID, "_"))
+		if name == "" || (name[0] >= '0' && name[0] <= '9') {
+			name = "_" + name
+		}
+		if previous, exists := seen[name]; exists {
+			issues = append(issues, FormatIssue{Row: i + 1, ID: record.ID, Reason: fmt.Sprintf("variable %s already used by %s", name, previous)})
+			continue
+		}
+		seen[name] = record.ID
+		if name != record.ID {
+			issues = append(issues, FormatIssue{Row: i + 1, ID: record.ID, Reason: fmt.Sprintf("exported as %s", name)})
+		}
+		fmt.Fprintf(&out, "%s=%s\n", name, strconv.Quote(record.Value))
+	}
+	return out.Bytes(), issues, nil
+}
+
+// generic CSV plus the 1Password and Bitwarden CSV exports, matched by header names
+var csvColumnAliases = map[string][]string{
+	"id":       {"id", "name", "title"},
+	"value":    {"value", "password", "login_password"},
+	"username": {"username", "login_username"},
+	"totp":     {"totp", "otpauth", "login_totp"},
+	"note":     {"note", "notes"},
+	"tags":     {"tags", "folder"},
+}
+
+func parseCSVSecrets(data []byte) ([]SecretRecord, []FormatIssue, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("CSV file is empty")
+	}
+	columns := make(map[string]int)
+	for i, header := range rows[0] {
+		header = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header, "\ufeff")))
+		for field, aliases := range csvColumnAliases {
+			if _, set := columns[field]; !set && slices.Contains(aliases, header) {
+				columns[field] = i
+			}
+		}
+	}
+	if _, ok := columns["id"]; !ok {
+		return nil, nil, fmt.Errorf("CSV header has no name/title/id column")
+	}
+	if _, ok := columns["value"]; !ok {
+		return nil, nil, fmt.Errorf("CSV header has no password/value column")
+	}
+	cell := func(row []string, field string) string {
+		idx, ok := columns[field]
+		if !ok || idx >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[idx])
+	}
+	collector := newRecordCollector()
+	for i, row := range rows[1:] {
+		rowNum := i + 2
+		id := cell(row, "id")
+		record := SecretRecord{ID: id, Value: cell(row, "value"), Note: cell(row, "note")}
+		if tags := cell(row, "tags"); tags != "" {
+			for tag := range strings.SplitSeq(tags, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					record.Tags = append(record.Tags, tag)
+				}
+			}
+		}
+		collector.add(rowNum, record)
+		if username := cell(row, "username"); username != "" && id != "" {
+			collector.add(rowNum, SecretRecord{ID: id + ".username", Value: username, Tags: record.Tags})
+		}
+		if totp := cell(row, "totp"); totp != "" && id != "" {
+			collector.add(rowNum, SecretRecord{ID: id + ".totp", Value: totp, Tags: record.Tags})
+		}
+	}
+	return collector.result()
+}
+
+func renderCSVSecrets(records []SecretRecord) ([]byte, []FormatIssue, error) {
+	var out bytes.Buffer
+	writer := csv.NewWriter(&out)
+	writer.Write([]string{"id", "value", "tags", "note"})
+	for _, record := range records {
+		writer.Write([]string{record.ID, record.Value, strings.Join(record.Tags, ","), record.Note})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return out.Bytes(), nil, nil
+}
@@ -0,0 +1,206 @@
+package anbuCrypto
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const maxSecretHistory = 10
+
+type SecretEntry struct {
+	Value     string          `json:"value"`
+	Kind      string          `json:"kind,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Tags      []string        `json:"tags,omitempty"`
+	Note      string          `json:"note,omitempty"`
+	History   []SecretVersion `json:"history,omitempty"`
+}
+
+type SecretVersion struct {
+	Value      string    `json:"value"`
+	SetAt      time.Time `json:"set_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+	Kind       string    `json:"kind,omitempty"`
+}
+
+type SecretHistoryItem struct {
+	Index      int
+	Kind       string
+	SetAt      time.Time
+	ReplacedAt time.Time
+	Value      string
+}
+
+// stores before v3 kept each secret as a bare ciphertext string
+func (e *SecretEntry) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*e = SecretEntry{Value: value}
+		return nil
+	}
+	type entryAlias SecretEntry
+	var entry entryAlias
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+	*e = SecretEntry(entry)
+	return nil
+}
+
+func (s *SecretsStore) setEntry(secretID, encryptedValue string, meta *SecretMetadata, now time.Time) {
+	entry, exists := s.Secrets[secretID]
+	if !exists {
+		entry = &SecretEntry{CreatedAt: now}
+		s.Secrets[secretID] = entry
+	} else {
+		setAt := entry.UpdatedAt
+		if setAt.IsZero() {
+			setAt = entry.CreatedAt
+		}
+		entry.History = append([]SecretVersion{{
+			Value:      entry.Value,
+			SetAt:      setAt,
+			ReplacedAt: now,
+			Kind:       entry.Kind,
+		}}, entry.History...)
+		if len(entry.History) > maxSecretHistory {
+			entry.History = entry.History[:maxSecretHistory]
+		}
+	}
+	entry.Value = encryptedValue
+	entry.UpdatedAt = now
+	if meta != nil {
+		if meta.Tags != nil {
+			entry.Tags = meta.Tags
+		}
+		if meta.Note != "" {
+			entry.Note = meta.Note
+		}
+		entry.Kind = meta.Kind
+	}
+}
+
+func (s *SecretsStore) reencrypt(oldKey, newKey []byte) error {
+	reencrypted := make(map[string]*SecretEntry, len(s.Secrets))
+	var failed []string
+	for id, entry := range s.Secrets {
+		updated := *entry
+		value, err := reencryptString(entry.Value, oldKey, newKey)
+		if err != nil {
+			failed = append(failed, id)
+			continue
+		}
+		updated.Value = value
+		updated.History = make([]SecretVersion, len(entry.History))
+		for i, version := range entry.History {
+			value, err := reencryptString(version.Value, oldKey, newKey)
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s (version %d)", id, i+1))
+				continue
+			}
+			version.Value = value
+			updated.History[i] = version
+		}
+		reencrypted[id] = &updated
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("%d entries failed to decrypt, nothing was changed: %s", len(failed), strings.Join(failed, ", "))
+	}
+	s.Secrets = reencrypted
+	return nil
+}
+
+func reencryptString(encryptedValue string, oldKey, newKey []byte) (string, error) {
+	value, err := decryptString(encryptedValue, oldKey)
+	if err != nil {
+		return "", err
+	}
+	return encryptString(value, newKey)
+}
+
+// versionKind falls back to the decrypted value for versions saved before the
+// kind was kept in history, which only matters for file attachments
+func versionKind(version SecretVersion, value string) string {
+	if version.Kind != "" {
+		return version.Kind
+	}
+	if _, ok := parseAttachmentRef(value); ok {
+		return SecretKindFile
+	}
+	return ""
+}
+
+func GetSecretHistory(filePath, secretID string, key []byte) ([]SecretHistoryItem, error) {
+	store, err := loadSecretsStore(filePath)
+	if err != nil {
+		return nil, err
+	}
+	entry, exists := store.Secrets[secretID]
+	if !exists {
+		return nil, fmt.Errorf("secret '%s' not found", secretID)
+	}
+	if key != nil {
+		if err := store.verifyKey(key); err != nil {
+			return nil, err
+		}
+	}
+	var items []SecretHistoryItem
+	for i, version := range entry.History {
+		item := SecretHistoryItem{
+			Index:      i + 1,
+			SetAt:      version.SetAt,
+			ReplacedAt: version.ReplacedAt,
+		}
+		if key != nil {
+			value, err := decryptString(version.Value, key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt version %d: %w", i+1, err)
+			}
+			item.Kind = versionKind(version, value)
+			item.Value = value
+			// never reveal the blob's file key
+			if ref, ok := parseAttachmentRef(value); ok {
+				item.Value = fmt.Sprintf("file %s (%d bytes, sha256 %s)", ref.Name, ref.Size, ref.SHA256)
+			}
+		} else {
+			item.Kind = version.Kind
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func RollbackSecret(filePath, secretID string, index int, key []byte) error {
+	unlock, err := lockSecretsStore(filePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	store, err := loadSecretsStore(filePath)
+	if err != nil {
+		return err
+	}
+	entry, exists := store.Secrets[secretID]
+	if !exists {
+		return fmt.Errorf("secret '%s' not found", secretID)
+	}
+	if index < 1 || index > len(entry.History) {
+		return fmt.Errorf("version %d not found (secret has %d previous versions)", index, len(entry.History))
+	}
+	if err := store.verifyKey(key); err != nil {
+		return err
+	}
+	version := entry.History[index-1]
+	value, err := decryptString(version.Value, key)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt version %d: %w", index, err)
+	}
+	entry.History = append(entry.History[:index-1], entry.History[index:]...)
+	store.setEntry(secretID, version.Value, &SecretMetadata{Kind: versionKind(version, value)}, time.Now())
+	return saveSecretsStore(store, filePath, key)
+}
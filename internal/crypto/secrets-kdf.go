@@ -0,0 +1,116 @@
+package anbuCrypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	KDFArgon2id     = "argon2id"
+	KDFPBKDF2SHA256 = "pbkdf2-sha256"
+
+	DefaultSecretsKDF = KDFArgon2id
+
+	secretsKeyLength  = 32
+	secretsSaltLength = 16
+)
+
+// v1 stores have no header and derive every key from this fixed salt
+var legacySecretsSalt = []byte("anbu-secrets-v1")
+
+type SecretsKDF struct {
+	Algorithm  string `json:"algorithm"`
+	Salt       string `json:"salt"`
+	Iterations uint32 `json:"iterations"`
+	Memory     uint32 `json:"memory,omitempty"`
+	Threads    uint8  `json:"threads,omitempty"`
+}
+
+type secretsKDFImpl struct {
+	defaults func() SecretsKDF
+	derive   func(password, salt []byte, params *SecretsKDF) ([]byte, error)
+}
+
+var secretsKDFs = map[string]secretsKDFImpl{
+	KDFArgon2id: {
+		defaults: func() SecretsKDF {
+			return SecretsKDF{Iterations: 3, Memory: 64 * 1024, Threads: 4}
+		},
+		derive: func(password, salt []byte, params *SecretsKDF) ([]byte, error) {
+			if params.Iterations == 0 || params.Iterations > 64 || params.Memory == 0 || params.Memory > 4*1024*1024 || params.Threads == 0 {
+				return nil, fmt.Errorf("invalid argon2id parameters")
+			}
+			return argon2.IDKey(password, salt, params.Iterations, params.Memory, params.Threads, secretsKeyLength), nil
+		},
+	},
+	KDFPBKDF2SHA256: {
+		defaults: func() SecretsKDF {
+			return SecretsKDF{Iterations: 600000}
+		},
+		derive: func(password, salt []byte, params *SecretsKDF) ([]byte, error) {
+			if params.Iterations == 0 || params.Iterations > 10000000 {
+				return nil, fmt.Errorf("invalid pbkdf2 parameters")
+			}
+			return pbkdf2.Key(password, salt, int(params.Iterations), secretsKeyLength, sha256.New), nil
+		},
+	},
+}
+
+func SupportedSecretsKDFs() []string {
+	var names []string
+	for name := range secretsKDFs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func NewSecretsKDF(algorithm string) (*SecretsKDF, error) {
+	impl, ok := secretsKDFs[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported KDF '%s'", algorithm)
+	}
+	salt := make([]byte, secretsSaltLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	params := impl.defaults()
+	params.Algorithm = algorithm
+	params.Salt = base64.StdEncoding.EncodeToString(salt)
+	return &params, nil
+}
+
+func (k *SecretsKDF) deriveKey(password string) ([]byte, error) {
+	impl, ok := secretsKDFs[k.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported KDF '%s'", k.Algorithm)
+	}
+	salt, err := base64.StdEncoding.DecodeString(k.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode KDF salt: %w", err)
+	}
+	if len(salt) < secretsSaltLength {
+		return nil, fmt.Errorf("KDF salt too short")
+	}
+	return impl.derive([]byte(password), salt, k)
+}
+
+func (k *SecretsKDF) String() string {
+	switch k.Algorithm {
+	case KDFArgon2id:
+		return fmt.Sprintf("%s (t=%d, m=%dKiB, p=%d)", k.Algorithm, k.Iterations, k.Memory, k.Threads)
+	default:
+		return fmt.Sprintf("%s (i=%d)", k.Algorithm, k.Iterations)
+	}
+}
+
+func legacySecretsKey(password string) []byte {
+	return pbkdf2.Key([]byte(password), legacySecretsSalt, 100000, secretsKeyLength, sha256.New)
+}
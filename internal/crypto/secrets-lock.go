@@ -0,0 +1,27 @@
+package anbuCrypto
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockSecretsStore takes an advisory exclusive lock on a sidecar file so
+// concurrent read-modify-write cycles on the same store are serialized
+func lockSecretsStore(filePath string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	file, err := os.OpenFile(filePath+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock secrets store: %w", err)
+	}
+	return func() {
+		unlockFile(file)
+		file.Close()
+	}, nil
+}
@@ -0,0 +1,60 @@
+package anbuCrypto
+
+import (
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// a check value with this plaintext marks a store whose MAC is mandatory, so
+// stripping the MAC field is detected like any other modification
+const secretsMACCheckValue = "anbu-secrets-check/mac"
+
+func secretsMACKey(key []byte) ([]byte, error) {
+	return hkdf.Key(sha256.New, key, nil, "anbu-secrets-mac", secretsKeyLength)
+}
+
+// encoding/json writes struct fields in declaration order and map keys sorted,
+// which makes the marshaled store (minus the MAC itself) a canonical form
+func (s *SecretsStore) computeMAC(key []byte) (string, error) {
+	macKey, err := secretsMACKey(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive MAC key: %w", err)
+	}
+	unsigned := *s
+	unsigned.MAC = ""
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal store: %w", err)
+	}
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(data)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (s *SecretsStore) sign(key []byte) error {
+	check, err := encryptString(secretsMACCheckValue, key)
+	if err != nil {
+		return err
+	}
+	s.Check = check
+	s.MAC, err = s.computeMAC(key)
+	return err
+}
+
+func (s *SecretsStore) verifyMAC(key []byte) error {
+	if s.MAC == "" {
+		return fmt.Errorf("secrets store MAC is missing, the file has been tampered with")
+	}
+	expected, err := s.computeMAC(key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(expected), []byte(s.MAC)) {
+		return fmt.Errorf("secrets store MAC mismatch, the file has been tampered with")
+	}
+	return nil
+}
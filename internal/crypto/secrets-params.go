@@ -4,36 +4,81 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-
-	"golang.org/x/crypto/pbkdf2"
+	"slices"
+	"sort"
+	"time"
 )
 
-var secretsSalt = []byte("anbu-secrets-v1")
+const (
+	secretsStoreVersion = 4
+	secretsCheckValue   = "anbu-secrets-check"
+)
 
 type SecretsStore struct {
-	Secrets map[string]string `json:"secrets"`
+	Version int                     `json:"version,omitempty"`
+	KDF     *SecretsKDF             `json:"kdf,omitempty"`
+	Check   string                  `json:"check,omitempty"`
+	MAC     string                  `json:"mac,omitempty"`
+	Secrets map[string]*SecretEntry `json:"secrets"`
+}
+
+// Tags replace the existing tags when non-nil, Note when non-empty, and Kind
+// is always written so a plain value can replace a TOTP seed
+type SecretMetadata struct {
+	Tags []string
+	Note string
+	Kind string
+}
+
+type SecretInfo struct {
+	ID        string
+	Kind      string
+	Tags      []string
+	Note      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Versions  int
+}
+
+type SecretsStoreInfo struct {
+	Version int
+	KDF     string
+	Entries int
 }
 
 func InitializeSecretsStore(filePath string) error {
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		kdf, err := NewSecretsKDF(DefaultSecretsKDF)
+		if err != nil {
+			return err
+		}
 		store := &SecretsStore{
-			Secrets: make(map[string]string),
+			Version: secretsStoreVersion,
+			KDF:     kdf,
+			Secrets: make(map[string]*SecretEntry),
 		}
-		if err := saveSecretsStore(store, filePath); err != nil {
+		if err := saveSecretsStore(store, filePath, nil); err != nil {
 			return fmt.Errorf("failed to create secrets store: %w", err)
 		}
 	}
 	return nil
 }
 
-func saveSecretsStore(store *SecretsStore, filePath string) error {
+func saveSecretsStore(store *SecretsStore, filePath string, key []byte) error {
+	if store.KDF != nil {
+		store.Version = secretsStoreVersion
+		if key != nil {
+			if err := store.sign(key); err != nil {
+				return err
+			}
+		}
+	}
 	dir := filepath.Dir(filePath)
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
@@ -42,54 +87,138 @@ func saveSecretsStore(store *SecretsStore, filePath string) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal store: %w", err)
 	}
-	if err := os.WriteFile(filePath, data, 0600); err != nil {
+	if err := writeFileAtomic(filePath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write secrets file: %w", err)
 	}
 	return nil
 }
 
-func ListSecrets(filePath string) ([]string, error) {
-	data, err := os.ReadFile(filePath)
+// a crash mid-write leaves either the old or the new file, never a partial one
+func writeFileAtomic(filePath string, data []byte, perm os.FileMode) error {
+	return writeStreamAtomic(filePath, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+func writeStreamAtomic(filePath string, perm os.FileMode, write func(io.Writer) error) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp-*")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+		return err
 	}
-	var store SecretsStore
-	if err := json.Unmarshal(data, &store); err != nil {
-		return nil, fmt.Errorf("failed to parse secrets file: %w", err)
+	tmpPath := tmpFile.Name()
+	cleanup := func(err error) error {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmpFile.Chmod(perm); err != nil {
+		return cleanup(err)
+	}
+	if err := write(tmpFile); err != nil {
+		return cleanup(err)
 	}
-	var secrets []string
-	for id := range store.Secrets {
-		secrets = append(secrets, id)
+	if err := tmpFile.Sync(); err != nil {
+		return cleanup(err)
 	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+func GetSecretsStoreInfo(filePath string) (*SecretsStoreInfo, error) {
+	store, err := loadSecretsStore(filePath)
+	if err != nil {
+		return nil, err
+	}
+	info := &SecretsStoreInfo{
+		Version: store.Version,
+		KDF:     "pbkdf2-sha256 (legacy static salt)",
+		Entries: len(store.Secrets),
+	}
+	if store.KDF != nil {
+		info.KDF = store.KDF.String()
+	}
+	return info, nil
+}
+
+func ListSecrets(filePath, tag string) ([]SecretInfo, error) {
+	store, err := loadSecretsStore(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var secrets []SecretInfo
+	for id, entry := range store.Secrets {
+		if tag != "" && !slices.Contains(entry.Tags, tag) {
+			continue
+		}
+		secrets = append(secrets, SecretInfo{
+			ID:        id,
+			Kind:      entry.Kind,
+			Tags:      entry.Tags,
+			Note:      entry.Note,
+			CreatedAt: entry.CreatedAt,
+			UpdatedAt: entry.UpdatedAt,
+			Versions:  len(entry.History) + 1,
+		})
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].ID < secrets[j].ID
+	})
 	return secrets, nil
 }
 
-func GetSecret(filePath, secretID, password string) (string, error) {
+func GetSecret(filePath, secretID string, key []byte) (string, error) {
 	store, err := loadSecretsStore(filePath)
 	if err != nil {
 		return "", err
 	}
-	encryptedValue, exists := store.Secrets[secretID]
+	entry, exists := store.Secrets[secretID]
 	if !exists {
 		return "", fmt.Errorf("secret '%s' not found", secretID)
 	}
-	return decryptString(encryptedValue, password)
+	if entry.Kind == SecretKindFile {
+		return "", fmt.Errorf("secret '%s' is a file attachment, use 'anbu pass extract'", secretID)
+	}
+	if err := store.verifyKey(key); err != nil {
+		return "", err
+	}
+	return decryptString(entry.Value, key)
 }
 
-func SetSecret(filePath, secretID, value, password string) error {
+func SetSecret(filePath, secretID, value string, meta *SecretMetadata, key []byte) error {
+	unlock, err := lockSecretsStore(filePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	store, err := loadSecretsStore(filePath)
 	if err != nil {
 		return err
 	}
-	encryptedValue, err := encryptString(value, password)
+	if err := store.verifyKey(key); err != nil {
+		return err
+	}
+	encryptedValue, err := encryptString(value, key)
 	if err != nil {
 		return fmt.Errorf("failed to encrypt secret: %w", err)
 	}
-	store.Secrets[secretID] = encryptedValue
-	return saveSecretsStore(store, filePath)
+	store.setEntry(secretID, encryptedValue, meta, time.Now())
+	return saveSecretsStore(store, filePath, key)
 }
 
-func DeleteSecret(filePath, secretID string) error {
+func DeleteSecret(filePath, secretID string, key []byte) error {
+	unlock, err := lockSecretsStore(filePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	store, err := loadSecretsStore(filePath)
 	if err != nil {
 		return err
@@ -97,67 +226,147 @@ func DeleteSecret(filePath, secretID string) error {
 	if _, exists := store.Secrets[secretID]; !exists {
 		return fmt.Errorf("secret '%s' not found", secretID)
 	}
+	if err := store.verifyKey(key); err != nil {
+		return err
+	}
 	delete(store.Secrets, secretID)
-	return saveSecretsStore(store, filePath)
+	if err := saveSecretsStore(store, filePath, key); err != nil {
+		return err
+	}
+	pruneAttachments(store, filePath, key)
+	return nil
 }
 
-func ImportSecrets(filePath, importFilePath string, password string) error {
-	importData, err := os.ReadFile(importFilePath)
+func loadSecretsStore(filePath string) (*SecretsStore, error) {
+	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to read import file: %w", err)
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
 	}
-	var importStore SecretsStore
-	if err := json.Unmarshal(importData, &importStore); err != nil {
-		return fmt.Errorf("failed to parse import file: %w", err)
+	var store SecretsStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets file: %w", err)
+	}
+	if store.Version == 0 {
+		store.Version = 1
+	}
+	if store.Version > secretsStoreVersion {
+		return nil, fmt.Errorf("secrets store version %d is newer than supported version %d", store.Version, secretsStoreVersion)
+	}
+	if store.Version > 1 && store.KDF == nil {
+		return nil, fmt.Errorf("secrets store is missing its KDF header")
+	}
+	if store.Version > 3 && store.MAC == "" && len(store.Secrets) > 0 {
+		return nil, fmt.Errorf("secrets store MAC is missing, the file has been tampered with")
+	}
+	if store.Secrets == nil {
+		store.Secrets = make(map[string]*SecretEntry)
 	}
-	currentStore, err := loadSecretsStore(filePath)
+	return &store, nil
+}
+
+// unlocking also upgrades stores that have no check value or MAC yet
+func UnlockSecretsStore(filePath, password string) ([]byte, error) {
+	unlock, err := lockSecretsStore(filePath)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer unlock()
+	store, err := loadSecretsStore(filePath)
+	if err != nil {
+		return nil, err
 	}
-	for secretID, secretValue := range importStore.Secrets {
-		if err := setSecret(currentStore, filePath, secretID, secretValue, password); err != nil {
-			return fmt.Errorf("failed to encrypt and save secret '%s': %w", secretID, err)
+	key, err := store.unlock(password)
+	if err != nil {
+		return nil, err
+	}
+	if store.KDF != nil && store.MAC == "" {
+		if err := saveSecretsStore(store, filePath, key); err != nil {
+			return nil, err
 		}
 	}
-	return nil
+	return key, nil
 }
 
-func ExportSecrets(filePath, exportFilePath string, password string) error {
-	store, err := loadSecretsStore(filePath)
+func (s *SecretsStore) unlock(password string) ([]byte, error) {
+	if s.KDF == nil {
+		return legacySecretsKey(password), nil
+	}
+	key, err := s.KDF.deriveKey(password)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to derive key: %w", err)
 	}
-	exportStore := &SecretsStore{
-		Secrets: make(map[string]string),
+	if err := s.verifyKey(key); err != nil {
+		return nil, err
 	}
-	for id, encryptedValue := range store.Secrets {
-		decryptedValue, err := decryptString(encryptedValue, password)
+	return key, nil
+}
+
+func (s *SecretsStore) verifyKey(key []byte) error {
+	if len(key) != secretsKeyLength {
+		return fmt.Errorf("invalid key length")
+	}
+	if s.KDF == nil {
+		return nil
+	}
+	if s.Check == "" {
+		if len(s.Secrets) > 0 {
+			return fmt.Errorf("secrets store is missing its key check")
+		}
+		check, err := encryptString(secretsCheckValue, key)
 		if err != nil {
-			return fmt.Errorf("failed to decrypt secret '%s': %w", id, err)
+			return err
 		}
-		exportStore.Secrets[id] = decryptedValue
+		s.Check = check
+		return nil
 	}
-	exportData, err := json.MarshalIndent(exportStore, "", "  ")
+	value, err := decryptString(s.Check, key)
 	if err != nil {
-		return fmt.Errorf("failed to marshal export data: %w", err)
+		return fmt.Errorf("incorrect password for secrets store")
+	}
+	switch {
+	case value == secretsMACCheckValue || s.MAC != "":
+		return s.verifyMAC(key)
+	case value == secretsCheckValue:
+		return nil
+	default:
+		return fmt.Errorf("incorrect password for secrets store")
 	}
-	return os.WriteFile(exportFilePath, exportData, 0600)
 }
 
-func loadSecretsStore(filePath string) (*SecretsStore, error) {
-	data, err := os.ReadFile(filePath)
+func MigrateSecretsStore(filePath, password, algorithm string) error {
+	unlock, err := lockSecretsStore(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+		return err
 	}
-	var store SecretsStore
-	if err := json.Unmarshal(data, &store); err != nil {
-		return nil, fmt.Errorf("failed to parse secrets file: %w", err)
+	defer unlock()
+	store, err := loadSecretsStore(filePath)
+	if err != nil {
+		return err
 	}
-	return &store, nil
+	oldKey, err := store.unlock(password)
+	if err != nil {
+		return err
+	}
+	kdf, err := NewSecretsKDF(algorithm)
+	if err != nil {
+		return err
+	}
+	migrated := &SecretsStore{
+		Version: secretsStoreVersion,
+		KDF:     kdf,
+		Secrets: store.Secrets,
+	}
+	newKey, err := kdf.deriveKey(password)
+	if err != nil {
+		return fmt.Errorf("failed to derive key: %w", err)
+	}
+	if err := migrated.reencrypt(oldKey, newKey); err != nil {
+		return err
+	}
+	return saveSecretsStore(migrated, filePath, newKey)
 }
 
-func encryptString(value, password string) (string, error) {
-	key := generateKeyFromPassword(password)
+func encryptString(value string, key []byte) (string, error) {
 	encryptedBytes, err := encryptData([]byte(value), key)
 	if err != nil {
 		return "", fmt.Errorf("failed to encrypt string: %w", err)
@@ -166,12 +375,11 @@ func encryptString(value, password string) (string, error) {
 	return encodedValue, nil
 }
 
-func decryptString(encryptedValue, password string) (string, error) {
+func decryptString(encryptedValue string, key []byte) (string, error) {
 	encryptedBytes, err := base64.StdEncoding.DecodeString(encryptedValue)
 	if err != nil {
 		return "", fmt.Errorf("failed to decode string: %w", err)
 	}
-	key := generateKeyFromPassword(password)
 	decryptedBytes, err := decryptData(encryptedBytes, key)
 	if err != nil {
 		return "", fmt.Errorf("failed to decrypt string: %w", err)
@@ -179,11 +387,15 @@ func decryptString(encryptedValue, password string) (string, error) {
 	return string(decryptedBytes), nil
 }
 
-func generateKeyFromPassword(password string) []byte {
-	return pbkdf2.Key([]byte(password), secretsSalt, 100000, 32, sha256.New)
+func encryptData(data []byte, key []byte) ([]byte, error) {
+	return sealWithAAD(data, key, nil)
 }
 
-func encryptData(data []byte, key []byte) ([]byte, error) {
+func decryptData(data []byte, key []byte) ([]byte, error) {
+	return openWithAAD(data, key, nil)
+}
+
+func sealWithAAD(data, key, aad []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cipher: %w", err)
@@ -196,11 +408,11 @@ func encryptData(data []byte, key []byte) ([]byte, error) {
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
-	ciphertext := gcm.Seal(nonce, nonce, data, nil)
+	ciphertext := gcm.Seal(nonce, nonce, data, aad)
 	return ciphertext, nil
 }
 
-func decryptData(data []byte, key []byte) ([]byte, error) {
+func openWithAAD(data, key, aad []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cipher: %w", err)
@@ -214,14 +426,5 @@ func decryptData(data []byte, key []byte) ([]byte, error) {
 		return nil, fmt.Errorf("ciphertext too short")
 	}
 	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-	return gcm.Open(nil, nonce, ciphertext, nil)
-}
-
-func setSecret(store *SecretsStore, filePath, secretID, value, password string) error {
-	encryptedValue, err := encryptString(value, password)
-	if err != nil {
-		return fmt.Errorf("failed to encrypt secret: %w", err)
-	}
-	store.Secrets[secretID] = encryptedValue
-	return saveSecretsStore(store, filePath)
+	return gcm.Open(nil, nonce, ciphertext, aad)
 }
@@ -0,0 +1,163 @@
+package anbuCrypto
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+	"text/template/parse"
+)
+
+func parseSecretsTemplate(templatePath string, secret func(string) (string, error)) (*template.Template, error) {
+	data, err := os.ReadFile(templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(templatePath)).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"secret": secret}).
+		Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// TemplateSecretRefs lists the secret names a template references with string
+// literals, either as {{ secret "NAME" }} or {{ "NAME" | secret }}
+func TemplateSecretRefs(templatePath string) ([]string, error) {
+	tmpl, err := parseSecretsTemplate(templatePath, func(string) (string, error) { return "", nil })
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			collectSecretRefs(t.Tree.Root, seen)
+		}
+	}
+	var refs []string
+	for name := range seen {
+		refs = append(refs, name)
+	}
+	sort.Strings(refs)
+	return refs, nil
+}
+
+func collectSecretRefs(node parse.Node, seen map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectSecretRefs(child, seen)
+		}
+	case *parse.ActionNode:
+		collectSecretRefs(n.Pipe, seen)
+	case *parse.IfNode:
+		collectSecretRefs(&n.BranchNode, seen)
+	case *parse.RangeNode:
+		collectSecretRefs(&n.BranchNode, seen)
+	case *parse.WithNode:
+		collectSecretRefs(&n.BranchNode, seen)
+	case *parse.BranchNode:
+		collectSecretRefs(n.Pipe, seen)
+		collectSecretRefs(n.List, seen)
+		collectSecretRefs(n.ElseList, seen)
+	case *parse.TemplateNode:
+		collectSecretRefs(n.Pipe, seen)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for i, cmd := range n.Cmds {
+			if i > 0 && isSecretIdent(cmd.Args[0]) && len(cmd.Args) == 1 && len(n.Cmds[i-1].Args) == 1 {
+				if name, ok := n.Cmds[i-1].Args[0].(*parse.StringNode); ok {
+					seen[name.Text] = true
+				}
+			}
+			collectSecretRefs(cmd, seen)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) == 2 && isSecretIdent(n.Args[0]) {
+			if name, ok := n.Args[1].(*parse.StringNode); ok {
+				seen[name.Text] = true
+			}
+		}
+		for _, arg := range n.Args {
+			collectSecretRefs(arg, seen)
+		}
+	}
+}
+
+func isSecretIdent(node parse.Node) bool {
+	ident, ok := node.(*parse.IdentifierNode)
+	return ok && ident.Ident == "secret"
+}
+
+// MissingTemplateSecrets only looks at the IDs in the store, nothing is decrypted
+func MissingTemplateSecrets(filePath, templatePath string) ([]string, []string, error) {
+	refs, err := TemplateSecretRefs(templatePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := loadSecretsStore(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	var missing []string
+	for _, name := range refs {
+		if _, exists := store.Secrets[name]; !exists {
+			missing = append(missing, name)
+		}
+	}
+	return refs, missing, nil
+}
+
+func RenderSecretsTemplate(filePath, templatePath, outputPath string, key []byte) (int, error) {
+	store, err := loadSecretsStore(filePath)
+	if err != nil {
+		return 0, err
+	}
+	if err := store.verifyKey(key); err != nil {
+		return 0, err
+	}
+	values := make(map[string]string)
+	secret := func(name string) (string, error) {
+		if value, cached := values[name]; cached {
+			return value, nil
+		}
+		entry, exists := store.Secrets[name]
+		if !exists {
+			return "", fmt.Errorf("secret '%s' not found", name)
+		}
+		if entry.Kind == SecretKindFile {
+			return "", fmt.Errorf("secret '%s' is a file attachment", name)
+		}
+		value, err := decryptString(entry.Value, key)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt secret '%s': %w", name, err)
+		}
+		values[name] = value
+		return value, nil
+	}
+	tmpl, err := parseSecretsTemplate(templatePath, secret)
+	if err != nil {
+		return 0, err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, nil); err != nil {
+		return 0, fmt.Errorf("failed to render template: %w", err)
+	}
+	if outputPath == "" {
+		_, err = os.Stdout.Write(out.Bytes())
+		return len(values), err
+	}
+	if err := writeFileAtomic(outputPath, out.Bytes(), 0600); err != nil {
+		return 0, fmt.Errorf("failed to write output: %w", err)
+	}
+	return len(values), nil
+}
@@ -0,0 +1,144 @@
+package anbuCrypto
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+const secretsMaskValue = "********"
+
+func RunWithSecrets(command []string, env map[string]string, mask bool) (int, error) {
+	if len(command) == 0 {
+		return 1, fmt.Errorf("no command provided")
+	}
+	child := exec.Command(command[0], command[1:]...)
+	child.Stdin = os.Stdin
+	child.Env = childEnvironment(env)
+
+	var stdout, stderr *maskWriter
+	if mask {
+		var values []string
+		for _, value := range env {
+			values = append(values, value)
+		}
+		stdout = newMaskWriter(os.Stdout, values)
+		stderr = newMaskWriter(os.Stderr, values)
+		child.Stdout = stdout
+		child.Stderr = stderr
+	} else {
+		child.Stdout = os.Stdout
+		child.Stderr = os.Stderr
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	defer signal.Stop(signals)
+	if err := child.Start(); err != nil {
+		return 1, fmt.Errorf("failed to start command: %w", err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				child.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := child.Wait()
+	if mask {
+		stdout.Flush()
+		stderr.Flush()
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if code := exitErr.ExitCode(); code >= 0 {
+				return code, nil
+			}
+			return 1, nil
+		}
+		return 1, fmt.Errorf("command failed: %w", err)
+	}
+	return 0, nil
+}
+
+func childEnvironment(secrets map[string]string) []string {
+	var env []string
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		if _, overridden := secrets[name]; overridden {
+			continue
+		}
+		env = append(env, entry)
+	}
+	for name, value := range secrets {
+		env = append(env, name+"="+value)
+	}
+	return env
+}
+
+type maskWriter struct {
+	out     io.Writer
+	secrets [][]byte
+	buf     []byte
+}
+
+func newMaskWriter(out io.Writer, secrets []string) *maskWriter {
+	w := &maskWriter{out: out}
+	for _, secret := range secrets {
+		if secret != "" {
+			w.secrets = append(w.secrets, []byte(secret))
+		}
+	}
+	return w
+}
+
+func (w *maskWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for _, secret := range w.secrets {
+		w.buf = bytes.ReplaceAll(w.buf, secret, []byte(secretsMaskValue))
+	}
+	// hold back any tail that could still grow into a secret on the next write
+	flush := len(w.buf) - w.pendingSuffix()
+	if flush > 0 {
+		if _, err := w.out.Write(w.buf[:flush]); err != nil {
+			return 0, err
+		}
+		w.buf = append(w.buf[:0], w.buf[flush:]...)
+	}
+	return len(p), nil
+}
+
+func (w *maskWriter) pendingSuffix() int {
+	longest := 0
+	for _, secret := range w.secrets {
+		for n := min(len(secret)-1, len(w.buf)); n > longest; n-- {
+			if bytes.HasSuffix(w.buf, secret[:n]) {
+				longest = n
+				break
+			}
+		}
+	}
+	return longest
+}
+
+func (w *maskWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.out.Write(w.buf)
+	w.buf = w.buf[:0]
+	return err
+}
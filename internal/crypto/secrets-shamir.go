@@ -0,0 +1,220 @@
+package anbuCrypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	shamirSharePrefix = "anbu-share"
+	shamirVersion     = "v1"
+	shamirMaxShares   = 255
+)
+
+type SecretShare struct {
+	SplitID   string
+	Threshold int
+	Index     int
+	Data      []byte
+}
+
+// GF(2^8) with the AES polynomial x^8 + x^4 + x^3 + x + 1 and generator 3
+var gfExp, gfLog = func() ([512]byte, [256]byte) {
+	var exp [512]byte
+	var log [256]byte
+	x := byte(1)
+	for i := range 255 {
+		exp[i] = x
+		log[x] = byte(i)
+		x ^= gfDouble(x)
+	}
+	for i := 255; i < 512; i++ {
+		exp[i] = exp[i-255]
+	}
+	return exp, log
+}()
+
+func gfDouble(x byte) byte {
+	if x&0x80 != 0 {
+		return x<<1 ^ 0x1b
+	}
+	return x << 1
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// SplitSecretValue splits value into shares of which any threshold
+// reconstruct it; every byte gets its own random polynomial
+func SplitSecretValue(value []byte, shares, threshold int) ([]string, error) {
+	if threshold < 2 || shares < threshold || shares > shamirMaxShares {
+		return nil, fmt.Errorf("need 2 <= threshold <= shares <= %d", shamirMaxShares)
+	}
+	if len(value) == 0 {
+		return nil, fmt.Errorf("cannot split an empty value")
+	}
+	splitID := make([]byte, 4)
+	if _, err := io.ReadFull(rand.Reader, splitID); err != nil {
+		return nil, fmt.Errorf("failed to generate split ID: %w", err)
+	}
+	coefficients := make([]byte, threshold-1)
+	points := make([][]byte, shares)
+	for i := range points {
+		points[i] = make([]byte, len(value))
+	}
+	for pos, secretByte := range value {
+		if _, err := io.ReadFull(rand.Reader, coefficients); err != nil {
+			return nil, fmt.Errorf("failed to generate coefficients: %w", err)
+		}
+		for i := range points {
+			x := byte(i + 1)
+			// Horner's rule from the highest coefficient down to the secret
+			y := byte(0)
+			for c := len(coefficients) - 1; c >= 0; c-- {
+				y = gfMul(y, x) ^ coefficients[c]
+			}
+			points[i][pos] = gfMul(y, x) ^ secretByte
+		}
+	}
+	clear(coefficients)
+	var encoded []string
+	for i, data := range points {
+		encoded = append(encoded, encodeShare(SecretShare{
+			SplitID:   hex.EncodeToString(splitID),
+			Threshold: threshold,
+			Index:     i + 1,
+			Data:      data,
+		}))
+	}
+	return encoded, nil
+}
+
+func encodeShare(share SecretShare) string {
+	body := fmt.Sprintf("%s:%s:%s:%d-%d:%s", shamirSharePrefix, shamirVersion, share.SplitID, share.Threshold, share.Index, base64.RawURLEncoding.EncodeToString(share.Data))
+	return body + ":" + shareChecksum(body)
+}
+
+func shareChecksum(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:4])
+}
+
+func ParseSecretShare(encoded string) (*SecretShare, error) {
+	encoded = strings.TrimSpace(encoded)
+	idx := strings.LastIndex(encoded, ":")
+	if idx < 0 {
+		return nil, fmt.Errorf("malformed share")
+	}
+	body, checksum := encoded[:idx], encoded[idx+1:]
+	if shareChecksum(body) != checksum {
+		return nil, fmt.Errorf("share checksum mismatch, the share is corrupted or mistyped")
+	}
+	parts := strings.Split(body, ":")
+	if len(parts) != 5 || parts[0] != shamirSharePrefix {
+		return nil, fmt.Errorf("malformed share")
+	}
+	if parts[1] != shamirVersion {
+		return nil, fmt.Errorf("unsupported share version '%s'", parts[1])
+	}
+	thresholdText, indexText, found := strings.Cut(parts[3], "-")
+	if !found {
+		return nil, fmt.Errorf("malformed share")
+	}
+	threshold, err := strconv.Atoi(thresholdText)
+	if err != nil || threshold < 2 || threshold > shamirMaxShares {
+		return nil, fmt.Errorf("invalid share threshold")
+	}
+	index, err := strconv.Atoi(indexText)
+	if err != nil || index < 1 || index > shamirMaxShares {
+		return nil, fmt.Errorf("invalid share index")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[4])
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("invalid share data")
+	}
+	return &SecretShare{SplitID: parts[2], Threshold: threshold, Index: index, Data: data}, nil
+}
+
+func CombineSecretShares(encoded []string) ([]byte, error) {
+	var shares []*SecretShare
+	seen := make(map[int]bool)
+	for i, text := range encoded {
+		share, err := ParseSecretShare(text)
+		if err != nil {
+			return nil, fmt.Errorf("share %d: %w", i+1, err)
+		}
+		if len(shares) > 0 {
+			first := shares[0]
+			if share.SplitID != first.SplitID {
+				return nil, fmt.Errorf("share %d belongs to split %s, not %s", i+1, share.SplitID, first.SplitID)
+			}
+			if share.Threshold != first.Threshold || len(share.Data) != len(first.Data) {
+				return nil, fmt.Errorf("share %d does not match the other shares", i+1)
+			}
+		}
+		if seen[share.Index] {
+			continue
+		}
+		seen[share.Index] = true
+		shares = append(shares, share)
+	}
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("no shares provided")
+	}
+	if len(shares) < shares[0].Threshold {
+		return nil, fmt.Errorf("need %d distinct shares, got %d", shares[0].Threshold, len(shares))
+	}
+	shares = shares[:shares[0].Threshold]
+	// Lagrange interpolation at x = 0
+	value := make([]byte, len(shares[0].Data))
+	for i, share := range shares {
+		xi := byte(share.Index)
+		basis := byte(1)
+		for j, other := range shares {
+			if i == j {
+				continue
+			}
+			xj := byte(other.Index)
+			basis = gfMul(basis, gfDiv(xj, xj^xi))
+		}
+		for pos := range value {
+			value[pos] ^= gfMul(share.Data[pos], basis)
+		}
+	}
+	return value, nil
+}
+
+func SplitSecret(filePath, secretID string, shares, threshold int, key []byte) ([]string, error) {
+	value, err := GetSecret(filePath, secretID, key)
+	if err != nil {
+		return nil, err
+	}
+	return SplitSecretValue([]byte(value), shares, threshold)
+}
+
+// CombineSecret stores the recovered value as a plain secret, replacing the
+// kind of any existing entry (e.g., an attachment or TOTP seed) under that ID
+func CombineSecret(filePath, secretID string, encoded []string, key []byte) error {
+	value, err := CombineSecretShares(encoded)
+	if err != nil {
+		return err
+	}
+	return SetSecret(filePath, secretID, string(value), &SecretMetadata{}, key)
+}
@@ -0,0 +1,158 @@
+package anbuCrypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const SecretKindTOTP = "totp"
+
+type TOTPConfig struct {
+	Secret    []byte
+	Algorithm string
+	Digits    int
+	Period    int
+	Issuer    string
+	Account   string
+}
+
+var totpAlgorithms = map[string]func() hash.Hash{
+	"SHA1":   sha1.New,
+	"SHA256": sha256.New,
+	"SHA512": sha512.New,
+}
+
+// ParseTOTP accepts an otpauth://totp URI or a bare base32 seed
+func ParseTOTP(value string) (*TOTPConfig, error) {
+	value = strings.TrimSpace(value)
+	config := &TOTPConfig{Algorithm: "SHA1", Digits: 6, Period: 30}
+	if !strings.HasPrefix(strings.ToLower(value), "otpauth://") {
+		secret, err := decodeTOTPSeed(value)
+		if err != nil {
+			return nil, err
+		}
+		config.Secret = secret
+		return config, nil
+	}
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid otpauth URI: %w", err)
+	}
+	if !strings.EqualFold(parsed.Host, "totp") {
+		return nil, fmt.Errorf("unsupported OTP type '%s', only totp is supported", parsed.Host)
+	}
+	label := strings.TrimPrefix(parsed.Path, "/")
+	if issuer, account, found := strings.Cut(label, ":"); found {
+		config.Issuer = strings.TrimSpace(issuer)
+		config.Account = strings.TrimSpace(account)
+	} else {
+		config.Account = label
+	}
+	query := parsed.Query()
+	if issuer := query.Get("issuer"); issuer != "" {
+		config.Issuer = issuer
+	}
+	if config.Secret, err = decodeTOTPSeed(query.Get("secret")); err != nil {
+		return nil, err
+	}
+	if algorithm := query.Get("algorithm"); algorithm != "" {
+		config.Algorithm = strings.ToUpper(algorithm)
+		if _, ok := totpAlgorithms[config.Algorithm]; !ok {
+			return nil, fmt.Errorf("unsupported TOTP algorithm '%s'", algorithm)
+		}
+	}
+	if digits := query.Get("digits"); digits != "" {
+		if config.Digits, err = strconv.Atoi(digits); err != nil || (config.Digits != 6 && config.Digits != 8) {
+			return nil, fmt.Errorf("TOTP digits must be 6 or 8")
+		}
+	}
+	if period := query.Get("period"); period != "" {
+		if config.Period, err = strconv.Atoi(period); err != nil || config.Period <= 0 || config.Period > 3600 {
+			return nil, fmt.Errorf("invalid TOTP period '%s'", period)
+		}
+	}
+	return config, nil
+}
+
+func decodeTOTPSeed(seed string) ([]byte, error) {
+	seed = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(seed))
+	seed = strings.TrimRight(seed, "=")
+	if seed == "" {
+		return nil, fmt.Errorf("TOTP seed is empty")
+	}
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(seed)
+	if err != nil {
+		return nil, fmt.Errorf("TOTP seed is not valid base32")
+	}
+	return secret, nil
+}
+
+func (c *TOTPConfig) URI() string {
+	label := c.Account
+	if c.Issuer != "" {
+		label = c.Issuer + ":" + c.Account
+	}
+	query := url.Values{}
+	query.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(c.Secret))
+	if c.Issuer != "" {
+		query.Set("issuer", c.Issuer)
+	}
+	query.Set("algorithm", c.Algorithm)
+	query.Set("digits", strconv.Itoa(c.Digits))
+	query.Set("period", strconv.Itoa(c.Period))
+	return (&url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + label, RawQuery: query.Encode()}).String()
+}
+
+// Code returns the RFC 6238 code valid at t and how long it stays valid
+func (c *TOTPConfig) Code(t time.Time) (string, time.Duration) {
+	period := int64(c.Period)
+	counter := t.Unix() / period
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], uint64(counter))
+	mac := hmac.New(totpAlgorithms[c.Algorithm], c.Secret)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulus := uint32(1)
+	for range c.Digits {
+		modulus *= 10
+	}
+	remaining := time.Duration((counter+1)*period)*time.Second - time.Duration(t.UnixNano())
+	return fmt.Sprintf("%0*d", c.Digits, value%modulus), remaining
+}
+
+func SetTOTPSecret(filePath, secretID, value string, meta *SecretMetadata, key []byte) error {
+	config, err := ParseTOTP(value)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		meta = &SecretMetadata{}
+	}
+	meta.Kind = SecretKindTOTP
+	return SetSecret(filePath, secretID, config.URI(), meta, key)
+}
+
+func GenerateTOTP(filePath, secretID string, key []byte) (string, time.Duration, error) {
+	value, err := GetSecret(filePath, secretID, key)
+	if err != nil {
+		return "", 0, err
+	}
+	config, err := ParseTOTP(value)
+	if err != nil {
+		return "", 0, fmt.Errorf("secret '%s' is not a TOTP seed: %w", secretID, err)
+	}
+	code, remaining := config.Code(time.Now())
+	return code, remaining, nil
+}
@@ -0,0 +1,166 @@
+package anbuCrypto
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	ConflictOverwrite = "overwrite"
+	ConflictSkip      = "skip"
+	ConflictRename    = "rename"
+)
+
+type SecretRecord struct {
+	ID    string   `json:"id"`
+	Value string   `json:"value"`
+	Kind  string   `json:"kind,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	Note  string   `json:"note,omitempty"`
+}
+
+type ImportResult struct {
+	ID       string
+	TargetID string
+	Action   string
+}
+
+type secretsExport struct {
+	Secrets map[string]string `json:"secrets"`
+}
+
+func ImportSecrets(filePath string, records []SecretRecord, policy string, dryRun bool, key []byte) ([]ImportResult, error) {
+	switch policy {
+	case ConflictOverwrite, ConflictSkip, ConflictRename:
+	default:
+		return nil, fmt.Errorf("unknown conflict policy '%s'", policy)
+	}
+	records, err := importableRecords(records)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := lockSecretsStore(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	currentStore, err := loadSecretsStore(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		if err := currentStore.verifyKey(key); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	var results []ImportResult
+	for _, record := range records {
+		result := ImportResult{ID: record.ID, TargetID: record.ID, Action: "add"}
+		if _, exists := currentStore.Secrets[record.ID]; exists {
+			switch policy {
+			case ConflictSkip:
+				result.Action = "skip"
+			case ConflictOverwrite:
+				result.Action = "overwrite"
+			case ConflictRename:
+				result.Action = "rename"
+				result.TargetID = nextFreeSecretID(currentStore, record.ID)
+			}
+		}
+		results = append(results, result)
+		if result.Action == "skip" {
+			continue
+		}
+		// placeholder entries keep later records in this batch from claiming the same renamed ID
+		encryptedValue := ""
+		if !dryRun {
+			encryptedValue, err = encryptString(record.Value, key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encrypt secret '%s': %w", record.ID, err)
+			}
+		}
+		currentStore.setEntry(result.TargetID, encryptedValue, &SecretMetadata{Tags: record.Tags, Note: record.Note, Kind: record.Kind}, now)
+	}
+	if dryRun {
+		return results, nil
+	}
+	return results, saveSecretsStore(currentStore, filePath, key)
+}
+
+// importableRecords checks kinds from the (untrusted) import file: file
+// attachments have no blob to point to, TOTP values must parse and any other
+// kind is dropped so the value is stored as a plain secret
+func importableRecords(records []SecretRecord) ([]SecretRecord, error) {
+	checked := make([]SecretRecord, len(records))
+	for i, record := range records {
+		switch record.Kind {
+		case "":
+		case SecretKindFile:
+			return nil, fmt.Errorf("secret '%s' is a file attachment, which cannot be imported", record.ID)
+		case SecretKindTOTP:
+			if _, err := ParseTOTP(record.Value); err != nil {
+				return nil, fmt.Errorf("secret '%s' has an invalid TOTP value: %w", record.ID, err)
+			}
+		default:
+			record.Kind = ""
+		}
+		checked[i] = record
+	}
+	return checked, nil
+}
+
+func nextFreeSecretID(store *SecretsStore, secretID string) string {
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", secretID, i)
+		if _, exists := store.Secrets[candidate]; !exists {
+			return candidate
+		}
+	}
+}
+
+// ExportSecretRecords decrypts every value. File attachments cannot be
+// carried in exports, so each one is reported as an issue instead
+func ExportSecretRecords(filePath string, key []byte) ([]SecretRecord, []FormatIssue, error) {
+	store, err := loadSecretsStore(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := store.verifyKey(key); err != nil {
+		return nil, nil, err
+	}
+	var records []SecretRecord
+	var skipped []FormatIssue
+	for id, entry := range store.Secrets {
+		if entry.Kind == SecretKindFile {
+			skipped = append(skipped, FormatIssue{ID: id, Reason: "file attachment not exported, use 'anbu pass extract'"})
+			continue
+		}
+		value, err := decryptString(entry.Value, key)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decrypt secret '%s': %w", id, err)
+		}
+		records = append(records, SecretRecord{ID: id, Value: value, Kind: entry.Kind, Tags: entry.Tags, Note: entry.Note})
+	}
+	sortSecretRecords(records)
+	sort.Slice(skipped, func(i, j int) bool {
+		return skipped[i].ID < skipped[j].ID
+	})
+	return records, skipped, nil
+}
+
+func ExportSecrets(filePath, exportFilePath, format string, key []byte) ([]FormatIssue, error) {
+	records, skipped, err := ExportSecretRecords(filePath, key)
+	if err != nil {
+		return nil, err
+	}
+	issues, err := WriteSecretsFile(exportFilePath, format, records)
+	return append(skipped, issues...), err
+}
+
+func sortSecretRecords(records []SecretRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+}
@@ -0,0 +1,181 @@
+package anbuCrypto
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const DefaultSecretsVault = "default"
+
+var vaultNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+func secretsConfigDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ".", nil
+	}
+	anbuDir := filepath.Join(homeDir, ".config", "anbu")
+	if err := os.MkdirAll(anbuDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create anbu directory: %w", err)
+	}
+	return anbuDir, nil
+}
+
+func SecretsVaultPath(name string) (string, error) {
+	anbuDir, err := secretsConfigDir()
+	if err != nil {
+		return "", err
+	}
+	if name == "" || name == DefaultSecretsVault {
+		return filepath.Join(anbuDir, "secrets.json"), nil
+	}
+	if !vaultNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid vault name '%s'", name)
+	}
+	vaultsDir := filepath.Join(anbuDir, "vaults")
+	if err := os.MkdirAll(vaultsDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create vaults directory: %w", err)
+	}
+	return filepath.Join(vaultsDir, name+".json"), nil
+}
+
+func ListSecretsVaults() ([]string, error) {
+	anbuDir, err := secretsConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	vaults := []string{DefaultSecretsVault}
+	matches, err := filepath.Glob(filepath.Join(anbuDir, "vaults", "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vaults: %w", err)
+	}
+	var named []string
+	for _, match := range matches {
+		named = append(named, strings.TrimSuffix(filepath.Base(match), ".json"))
+	}
+	sort.Strings(named)
+	return append(vaults, named...), nil
+}
+
+func CreateSecretsVault(name, password string) (string, error) {
+	filePath, err := SecretsVaultPath(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(filePath); err == nil {
+		return "", fmt.Errorf("vault '%s' already exists", name)
+	}
+	if err := InitializeSecretsStore(filePath); err != nil {
+		return "", err
+	}
+	if _, err := UnlockSecretsStore(filePath, password); err != nil {
+		os.Remove(filePath)
+		return "", err
+	}
+	return filePath, nil
+}
+
+func DeleteSecretsVault(name string, force bool) error {
+	if name == "" || name == DefaultSecretsVault {
+		return fmt.Errorf("the default vault cannot be deleted")
+	}
+	filePath, err := SecretsVaultPath(name)
+	if err != nil {
+		return err
+	}
+	store, err := loadSecretsStore(filePath)
+	if err != nil {
+		return err
+	}
+	if len(store.Secrets) > 0 && !force {
+		return fmt.Errorf("vault '%s' still holds %d secrets", name, len(store.Secrets))
+	}
+	if err := os.Remove(filePath); err != nil {
+		return fmt.Errorf("failed to delete vault: %w", err)
+	}
+	os.Remove(filePath + ".lock")
+	os.RemoveAll(attachmentsDir(filePath))
+	return nil
+}
+
+func RekeySecretsStore(filePath string, oldKey []byte, newPassword string) error {
+	unlock, err := lockSecretsStore(filePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	store, err := loadSecretsStore(filePath)
+	if err != nil {
+		return err
+	}
+	if err := store.verifyKey(oldKey); err != nil {
+		return err
+	}
+	algorithm := DefaultSecretsKDF
+	if store.KDF != nil {
+		algorithm = store.KDF.Algorithm
+	}
+	kdf, err := NewSecretsKDF(algorithm)
+	if err != nil {
+		return err
+	}
+	rekeyed := &SecretsStore{
+		Version: secretsStoreVersion,
+		KDF:     kdf,
+		Secrets: store.Secrets,
+	}
+	newKey, err := kdf.deriveKey(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to derive key: %w", err)
+	}
+	if err := rekeyed.reencrypt(oldKey, newKey); err != nil {
+		return err
+	}
+	return saveSecretsStore(rekeyed, filePath, newKey)
+}
+
+func CopySecret(srcPath, dstPath, secretID, targetID string, srcKey, dstKey []byte) error {
+	srcStore, err := loadSecretsStore(srcPath)
+	if err != nil {
+		return err
+	}
+	entry, exists := srcStore.Secrets[secretID]
+	if !exists {
+		return fmt.Errorf("secret '%s' not found", secretID)
+	}
+	if err := srcStore.verifyKey(srcKey); err != nil {
+		return err
+	}
+	unlock, err := lockSecretsStore(dstPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	dstStore, err := loadSecretsStore(dstPath)
+	if err != nil {
+		return err
+	}
+	if err := dstStore.verifyKey(dstKey); err != nil {
+		return err
+	}
+	if entry.Kind == SecretKindFile {
+		if err := copyAttachmentBlob(srcPath, dstPath, entry.Value, srcKey); err != nil {
+			return err
+		}
+	}
+	encryptedValue, err := reencryptString(entry.Value, srcKey, dstKey)
+	if err != nil {
+		return fmt.Errorf("failed to re-encrypt secret '%s': %w", secretID, err)
+	}
+	if targetID == "" {
+		targetID = secretID
+	}
+	meta := &SecretMetadata{Tags: entry.Tags, Note: entry.Note, Kind: entry.Kind}
+	dstStore.setEntry(targetID, encryptedValue, meta, time.Now())
+	return saveSecretsStore(dstStore, dstPath, dstKey)
+}
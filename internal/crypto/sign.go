@@ -0,0 +1,342 @@
+package anbuCrypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	SignFormatRaw = "raw"
+	SignFormatSSH = "ssh"
+
+	DefaultSignNamespace = "file"
+	sshSigMagic          = "SSHSIG"
+	sshSigArmorType      = "SSH SIGNATURE"
+)
+
+var SignFormats = []string{SignFormatRaw, SignFormatSSH}
+
+type SignOptions struct {
+	KeyPath    string
+	Passphrase string
+	Format     string
+	Namespace  string
+	OutputPath string
+}
+
+type SignatureResult struct {
+	SignaturePath string
+	Format        string
+	Algorithm     string
+	Namespace     string
+	Fingerprint   string
+}
+
+// sshSigBlob and sshSigSignedData follow PROTOCOL.sshsig; both are
+// preceded by the 6 byte magic preamble
+type sshSigBlob struct {
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+type sshSigSignedData struct {
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          []byte
+}
+
+func hashFile(path string, h hash.Hash) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(h, file); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return h.Sum(nil), nil
+}
+
+// rawSignatureHash picks the digest for raw signatures: SHA-256 for RSA-PSS
+// and P-256, SHA-384 for P-384. Ed25519 signs the message itself
+func rawSignatureHash(publicKey crypto.PublicKey) (crypto.Hash, string, error) {
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		return crypto.SHA256, "rsa-pss-sha256", nil
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P384() {
+			return crypto.SHA384, "ecdsa-sha384", nil
+		}
+		return crypto.SHA256, "ecdsa-sha256", nil
+	case ed25519.PublicKey:
+		return 0, KeyTypeEd25519, nil
+	default:
+		return 0, "", fmt.Errorf("unsupported key type %T", publicKey)
+	}
+}
+
+func signRaw(path string, key crypto.Signer) ([]byte, string, error) {
+	hashFunc, algorithm, err := rawSignatureHash(key.Public())
+	if err != nil {
+		return nil, "", err
+	}
+	if hashFunc == 0 {
+		message, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read file: %w", err)
+		}
+		signature, err := key.Sign(rand.Reader, message, crypto.Hash(0))
+		return signature, algorithm, err
+	}
+	digest, err := hashFile(path, hashFunc.New())
+	if err != nil {
+		return nil, "", err
+	}
+	var opts crypto.SignerOpts = hashFunc
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hashFunc}
+	}
+	signature, err := key.Sign(rand.Reader, digest, opts)
+	return signature, algorithm, err
+}
+
+func verifyRaw(path string, publicKey crypto.PublicKey, signature []byte) (string, error) {
+	hashFunc, algorithm, err := rawSignatureHash(publicKey)
+	if err != nil {
+		return "", err
+	}
+	if hashFunc == 0 {
+		message, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+		if !ed25519.Verify(publicKey.(ed25519.PublicKey), message, signature) {
+			return "", fmt.Errorf("signature does not match")
+		}
+		return algorithm, nil
+	}
+	digest, err := hashFile(path, hashFunc.New())
+	if err != nil {
+		return "", err
+	}
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPSS(k, hashFunc, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, digest, signature) {
+			err = fmt.Errorf("invalid signature")
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("signature does not match")
+	}
+	return algorithm, nil
+}
+
+func sshSigMessage(namespace, hashAlgorithm string, digest []byte) []byte {
+	return append([]byte(sshSigMagic), ssh.Marshal(sshSigSignedData{
+		Namespace:     namespace,
+		HashAlgorithm: hashAlgorithm,
+		Hash:          digest,
+	})...)
+}
+
+// signSSH produces an armored signature that `ssh-keygen -Y verify` accepts;
+// RSA keys sign with rsa-sha2-512 as ssh-keygen does
+func signSSH(path string, key crypto.Signer, namespace string) ([]byte, string, error) {
+	signer, err := ssh.NewSignerFromSigner(key)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create SSH signer: %w", err)
+	}
+	digest, err := hashFile(path, sha512.New())
+	if err != nil {
+		return nil, "", err
+	}
+	message := sshSigMessage(namespace, "sha512", digest)
+	var signature *ssh.Signature
+	if algorithmSigner, ok := signer.(ssh.AlgorithmSigner); ok && signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		signature, err = algorithmSigner.SignWithAlgorithm(rand.Reader, message, ssh.KeyAlgoRSASHA512)
+	} else {
+		signature, err = signer.Sign(rand.Reader, message)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to sign: %w", err)
+	}
+	blob := append([]byte(sshSigMagic), ssh.Marshal(sshSigBlob{
+		Version:       1,
+		PublicKey:     signer.PublicKey().Marshal(),
+		Namespace:     namespace,
+		HashAlgorithm: "sha512",
+		Signature:     ssh.Marshal(signature),
+	})...)
+	return pem.EncodeToMemory(&pem.Block{Type: sshSigArmorType, Bytes: blob}), signature.Format, nil
+}
+
+func verifySSH(path string, publicKey crypto.PublicKey, armored []byte, namespace string) (string, error) {
+	block, _ := pem.Decode(armored)
+	if block == nil || block.Type != sshSigArmorType || !bytes.HasPrefix(block.Bytes, []byte(sshSigMagic)) {
+		return "", fmt.Errorf("malformed SSH signature")
+	}
+	var blob sshSigBlob
+	if err := ssh.Unmarshal(block.Bytes[len(sshSigMagic):], &blob); err != nil {
+		return "", fmt.Errorf("malformed SSH signature: %w", err)
+	}
+	if blob.Version != 1 {
+		return "", fmt.Errorf("unsupported SSH signature version %d", blob.Version)
+	}
+	if blob.Namespace != namespace {
+		return "", fmt.Errorf("signature namespace is '%s', expected '%s'", blob.Namespace, namespace)
+	}
+	expected, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to create SSH public key: %w", err)
+	}
+	if !bytes.Equal(blob.PublicKey, expected.Marshal()) {
+		signer, err := ssh.ParsePublicKey(blob.PublicKey)
+		if err != nil {
+			return "", fmt.Errorf("signature was made by an unreadable key")
+		}
+		return "", fmt.Errorf("signature was made by %s, not the given key", ssh.FingerprintSHA256(signer))
+	}
+	var h hash.Hash
+	switch blob.HashAlgorithm {
+	case "sha512":
+		h = sha512.New()
+	case "sha256":
+		h = sha256.New()
+	default:
+		return "", fmt.Errorf("unsupported hash algorithm '%s'", blob.HashAlgorithm)
+	}
+	var signature ssh.Signature
+	if err := ssh.Unmarshal(blob.Signature, &signature); err != nil {
+		return "", fmt.Errorf("malformed SSH signature: %w", err)
+	}
+	// SSHSIG forbids SHA-1, ssh-keygen -Y verify rejects ssh-rsa as well
+	if signature.Format == ssh.KeyAlgoRSA {
+		return "", fmt.Errorf("ssh-rsa (SHA-1) signatures are not accepted, use rsa-sha2-256 or rsa-sha2-512")
+	}
+	digest, err := hashFile(path, h)
+	if err != nil {
+		return "", err
+	}
+	if err := expected.Verify(sshSigMessage(blob.Namespace, blob.HashAlgorithm, digest), &signature); err != nil {
+		return "", fmt.Errorf("signature does not match")
+	}
+	return signature.Format, nil
+}
+
+// SignFile writes a detached signature to <file>.sig. Without an explicit
+// format OpenSSH keys produce SSHSIG signatures and PEM keys raw ones
+func SignFile(path string, opts SignOptions) (*SignatureResult, error) {
+	key, err := loadKeyMaterial(opts.KeyPath, opts.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if key.private == nil {
+		if key.encrypted {
+			return nil, ErrPassphraseRequired
+		}
+		return nil, fmt.Errorf("%s is a public key, signing needs the private key", opts.KeyPath)
+	}
+	format := opts.Format
+	if format == "" {
+		format = SignFormatRaw
+		if key.format == KeyFormatOpenSSH {
+			format = SignFormatSSH
+		}
+	}
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = DefaultSignNamespace
+	}
+	var signature []byte
+	var algorithm string
+	switch format {
+	case SignFormatRaw:
+		signature, algorithm, err = signRaw(path, key.private)
+		namespace = ""
+	case SignFormatSSH:
+		signature, algorithm, err = signSSH(path, key.private, namespace)
+	default:
+		return nil, fmt.Errorf("unsupported signature format '%s'", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	outputPath := opts.OutputPath
+	if outputPath == "" {
+		outputPath = path + ".sig"
+	}
+	if err := writeFileAtomic(outputPath, signature, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write signature: %w", err)
+	}
+	fingerprint, err := publicKeyFingerprint(key.public)
+	if err != nil {
+		return nil, err
+	}
+	return &SignatureResult{
+		SignaturePath: outputPath,
+		Format:        format,
+		Algorithm:     algorithm,
+		Namespace:     namespace,
+		Fingerprint:   fingerprint,
+	}, nil
+}
+
+// VerifyFile checks a detached raw or SSHSIG signature against a public key;
+// the format is detected from the signature file
+func VerifyFile(path, signaturePath, publicKeyPath, passphrase, namespace string) (*SignatureResult, error) {
+	signature, err := os.ReadFile(signaturePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature: %w", err)
+	}
+	key, err := loadKeyMaterial(publicKeyPath, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		namespace = DefaultSignNamespace
+	}
+	result := &SignatureResult{SignaturePath: signaturePath}
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte("-----BEGIN "+sshSigArmorType)) {
+		result.Format, result.Namespace = SignFormatSSH, namespace
+		result.Algorithm, err = verifySSH(path, key.public, signature, namespace)
+	} else {
+		result.Format = SignFormatRaw
+		result.Algorithm, err = verifyRaw(path, key.public, signature)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if result.Fingerprint, err = publicKeyFingerprint(key.public); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func publicKeyFingerprint(publicKey crypto.PublicKey) (string, error) {
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to create SSH public key: %w", err)
+	}
+	return ssh.FingerprintSHA256(sshPublicKey), nil
+}
@@ -0,0 +1,361 @@
+package anbuGenerics
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"mime/quotedprintable"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+type codec struct {
+	encode func([]byte) ([]byte, error)
+	decode func([]byte) ([]byte, error)
+}
+
+var EncodingNames = []string{"b64", "b64url", "b64raw", "b32", "hex", "url", "html", "qp", "gzip", "gzb64", "unicode"}
+
+var encodingAliases = map[string]string{
+	"base64":           "b64",
+	"base64url":        "b64url",
+	"base64raw":        "b64raw",
+	"base32":           "b32",
+	"percent":          "url",
+	"quoted-printable": "qp",
+	"gunzip":           "gzip",
+	"uni":              "unicode",
+}
+
+var codecs = map[string]codec{
+	"b64": {
+		encode: func(data []byte) ([]byte, error) { return []byte(base64.StdEncoding.EncodeToString(data)), nil },
+		decode: func(data []byte) ([]byte, error) { return decodeBase64(data, base64.StdEncoding) },
+	},
+	"b64url": {
+		encode: func(data []byte) ([]byte, error) { return []byte(base64.URLEncoding.EncodeToString(data)), nil },
+		decode: func(data []byte) ([]byte, error) { return decodeBase64(data, base64.URLEncoding) },
+	},
+	"b64raw": {
+		encode: func(data []byte) ([]byte, error) { return []byte(base64.RawStdEncoding.EncodeToString(data)), nil },
+		decode: func(data []byte) ([]byte, error) { return decodeBase64(data, base64.StdEncoding) },
+	},
+	"b32": {
+		encode: func(data []byte) ([]byte, error) { return []byte(base32.StdEncoding.EncodeToString(data)), nil },
+		decode: decodeBase32,
+	},
+	"hex": {
+		encode: func(data []byte) ([]byte, error) { return []byte(hex.EncodeToString(data)), nil },
+		decode: decodeHex,
+	},
+	"url": {
+		encode: func(data []byte) ([]byte, error) {
+			return []byte(strings.ReplaceAll(url.QueryEscape(string(data)), "+", "%20")), nil
+		},
+		decode: func(data []byte) ([]byte, error) {
+			decoded, err := url.QueryUnescape(string(data))
+			return []byte(decoded), err
+		},
+	},
+	"html": {
+		encode: func(data []byte) ([]byte, error) { return []byte(html.EscapeString(string(data))), nil },
+		decode: func(data []byte) ([]byte, error) { return []byte(html.UnescapeString(string(data))), nil },
+	},
+	"qp": {
+		encode: encodeQuotedPrintable,
+		decode: func(data []byte) ([]byte, error) {
+			return io.ReadAll(quotedprintable.NewReader(bytes.NewReader(data)))
+		},
+	},
+	"gzip": {
+		encode: gzipBytes,
+		decode: gunzipBytes,
+	},
+	"gzb64": {
+		encode: func(data []byte) ([]byte, error) {
+			compressed, err := gzipBytes(data)
+			return []byte(base64.StdEncoding.EncodeToString(compressed)), err
+		},
+		decode: func(data []byte) ([]byte, error) {
+			compressed, err := decodeBase64(data, base64.StdEncoding)
+			if err != nil {
+				return nil, err
+			}
+			return gunzipBytes(compressed)
+		},
+	},
+	"unicode": {
+		encode: encodeUnicodeEscapes,
+		decode: decodeUnicodeEscapes,
+	},
+}
+
+func stripWhitespace(data []byte) string {
+	return strings.Join(strings.Fields(string(data)), "")
+}
+
+// decodeBase64 accepts padded and unpadded input, wrapped over several lines
+func decodeBase64(data []byte, encoding *base64.Encoding) ([]byte, error) {
+	text := stripWhitespace(data)
+	if strings.HasSuffix(text, "=") {
+		return encoding.DecodeString(text)
+	}
+	return encoding.WithPadding(base64.NoPadding).DecodeString(text)
+}
+
+func decodeBase32(data []byte) ([]byte, error) {
+	text := strings.ToUpper(strings.TrimRight(stripWhitespace(data), "="))
+	return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(text)
+}
+
+// decodeHex ignores whitespace, colons and a 0x prefix, as in "0xde ad:be:ef"
+func decodeHex(data []byte) ([]byte, error) {
+	text := strings.ReplaceAll(stripWhitespace(data), ":", "")
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "0x"), "0X")
+	return hex.DecodeString(text)
+}
+
+func encodeQuotedPrintable(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := quotedprintable.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzipBytes(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// encodeUnicodeEscapes writes everything outside printable ASCII as \uXXXX,
+// using surrogate pairs above U+FFFF like JSON and JavaScript do
+func encodeUnicodeEscapes(data []byte) ([]byte, error) {
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("input is not valid UTF-8")
+	}
+	var builder strings.Builder
+	for _, r := range string(data) {
+		switch {
+		case r >= 0x20 && r < 0x7f && r != '\\':
+			builder.WriteRune(r)
+		case r > 0xffff:
+			high, low := utf16.EncodeRune(r)
+			fmt.Fprintf(&builder, "\\u%04x\\u%04x", high, low)
+		default:
+			fmt.Fprintf(&builder, "\\u%04x", r)
+		}
+	}
+	return []byte(builder.String()), nil
+}
+
+// decodeUnicodeEscapes understands \uXXXX (with surrogate pairs), \u{X...},
+// \UXXXXXXXX and \xXX; any other backslash is kept as is
+func decodeUnicodeEscapes(data []byte) ([]byte, error) {
+	text := string(data)
+	var out []byte
+	for i := 0; i < len(text); {
+		if text[i] != '\\' || i+1 >= len(text) {
+			out = append(out, text[i])
+			i++
+			continue
+		}
+		var digits string
+		width := 0
+		switch text[i+1] {
+		case 'x':
+			digits, width = substr(text, i+2, 2), 4
+		case 'U':
+			digits, width = substr(text, i+2, 8), 10
+		case 'u':
+			if strings.HasPrefix(text[i+2:], "{") {
+				if end := strings.IndexByte(text[i+2:], '}'); end > 1 {
+					digits, width = text[i+3:i+2+end], end+3
+				}
+			} else {
+				digits, width = substr(text, i+2, 4), 6
+			}
+		}
+		value, err := strconv.ParseUint(digits, 16, 32)
+		if width == 0 || err != nil {
+			out = append(out, text[i])
+			i++
+			continue
+		}
+		if text[i+1] == 'x' {
+			out = append(out, byte(value))
+			i += width
+			continue
+		}
+		r := rune(value)
+		if utf16.IsSurrogate(r) && strings.HasPrefix(text[i+width:], "\\u") {
+			if low, err := strconv.ParseUint(substr(text, i+width+2, 4), 16, 32); err == nil {
+				if paired := utf16.DecodeRune(r, rune(low)); paired != unicode.ReplacementChar {
+					r = paired
+					width += 6
+				}
+			}
+		}
+		out = utf8.AppendRune(out, r)
+		i += width
+	}
+	return out, nil
+}
+
+func substr(text string, start, length int) string {
+	if start+length > len(text) {
+		return ""
+	}
+	return text[start : start+length]
+}
+
+func resolveEncoding(name string) (codec, string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := encodingAliases[name]; ok {
+		name = alias
+	}
+	c, ok := codecs[name]
+	if !ok {
+		return codec{}, "", fmt.Errorf("unknown encoding '%s', use one of %s", name, strings.Join(EncodingNames, ", "))
+	}
+	return c, name, nil
+}
+
+// ParseEncodingChain splits a --chain value such as "b64,gunzip" into
+// encoding names, applied left to right
+func ParseEncodingChain(chain string) ([]string, error) {
+	var steps []string
+	for part := range strings.SplitSeq(chain, ",") {
+		_, name, err := resolveEncoding(part)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, name)
+	}
+	return steps, nil
+}
+
+func EncodeChain(steps []string, data []byte) ([]byte, error) {
+	for _, step := range steps {
+		c, name, err := resolveEncoding(step)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = c.encode(data); err != nil {
+			return nil, fmt.Errorf("%s encode failed: %w", name, err)
+		}
+	}
+	return data, nil
+}
+
+func DecodeChain(steps []string, data []byte) ([]byte, error) {
+	for _, step := range steps {
+		c, name, err := resolveEncoding(step)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = c.decode(data); err != nil {
+			return nil, fmt.Errorf("%s decode failed: %w", name, err)
+		}
+	}
+	return data, nil
+}
+
+// IsReadableText reports whether data is UTF-8 made of printable characters
+// and whitespace, i.e., safe to print to a terminal
+func IsReadableText(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isGzip(data []byte) bool {
+	return len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b
+}
+
+var guessPatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"hex", regexp.MustCompile(`^(0[xX])?([0-9a-fA-F]{2}[\s:]?)+$`)},
+	{"b32", regexp.MustCompile(`^[A-Z2-7\s]{8,}=*$`)},
+	{"b64", regexp.MustCompile(`^[A-Za-z0-9+/\s]{4,}={0,2}$`)},
+	{"b64url", regexp.MustCompile(`^[A-Za-z0-9_\-\s]{4,}={0,2}$`)},
+	{"url", regexp.MustCompile(`%[0-9a-fA-F]{2}`)},
+	{"html", regexp.MustCompile(`&(#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z]+);`)},
+	{"unicode", regexp.MustCompile(`\\(u[0-9a-fA-F]{4}|u\{[0-9a-fA-F]+\}|U[0-9a-fA-F]{8}|x[0-9a-fA-F]{2})`)},
+	{"qp", regexp.MustCompile(`=([0-9A-F]{2}|\r?\n)`)},
+}
+
+// GuessDecode peels off layers of encoding until the data stops changing
+// or no decoder gives readable output. Base encodings are only accepted when
+// they decode to text or to gzip data, which then gets decompressed
+func GuessDecode(data []byte) ([]byte, []string) {
+	var steps []string
+	for len(steps) < 10 {
+		if isGzip(data) {
+			decoded, err := gunzipBytes(data)
+			if err != nil {
+				break
+			}
+			steps, data = append(steps, "gunzip"), decoded
+			continue
+		}
+		text := bytes.TrimSpace(data)
+		found := false
+		for _, guess := range guessPatterns {
+			if !guess.pattern.Match(text) {
+				continue
+			}
+			decoded, err := codecs[guess.name].decode(text)
+			if err != nil || len(decoded) == 0 || bytes.Equal(decoded, text) {
+				continue
+			}
+			if !IsReadableText(decoded) && !isGzip(decoded) {
+				continue
+			}
+			steps, data, found = append(steps, guess.name), decoded, true
+			break
+		}
+		if !found {
+			break
+		}
+	}
+	return data, steps
+}
@@ -0,0 +1,72 @@
+package anbuGenerics
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestEncodingRoundTrip(t *testing.T) {
+	input := []byte("héllo <w&rld> ?=/+ 😀 {\"a\":1}")
+	for _, name := range EncodingNames {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := EncodeChain([]string{name}, input)
+			if err != nil {
+				t.Fatalf("EncodeChain(%s) error = %v", name, err)
+			}
+			decoded, err := DecodeChain([]string{name}, encoded)
+			if err != nil {
+				t.Fatalf("DecodeChain(%s) error = %v", name, err)
+			}
+			if !bytes.Equal(decoded, input) {
+				t.Errorf("DecodeChain(%s) = %q, want %q", name, decoded, input)
+			}
+		})
+	}
+}
+
+func TestDecodeLenientInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		input    string
+		want     string
+	}{
+		{"b64 unpadded", "b64", "aGVsbG8", "hello"},
+		{"b64 wrapped", "base64", "aGVs\nbG8=", "hello"},
+		{"b32 lowercase", "b32", "nbswy3dp", "hello"},
+		{"hex separators", "hex", "0x68:65 6c:6c:6f", "hello"},
+		{"unicode braces", "unicode", `\u{1F600} \x41\U0001F600`, "😀 A😀"},
+		{"unicode stray backslash", "unicode", `C:\users`, `C:\users`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeChain([]string{tt.encoding}, []byte(tt.input))
+			if err != nil || string(got) != tt.want {
+				t.Errorf("DecodeChain(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodingChainAndGuess(t *testing.T) {
+	if _, err := ParseEncodingChain("b64,nope"); err == nil {
+		t.Errorf("ParseEncodingChain accepted an unknown encoding")
+	}
+	steps, err := ParseEncodingChain("base64, gunzip")
+	if err != nil || !slices.Equal(steps, []string{"b64", "gzip"}) {
+		t.Fatalf("ParseEncodingChain = %v, %v", steps, err)
+	}
+	input := []byte(`{"user":"anbu","roles":["admin"]}`)
+	encoded, err := EncodeChain([]string{"gzip", "b64", "url"}, input)
+	if err != nil {
+		t.Fatalf("EncodeChain error = %v", err)
+	}
+	decoded, guessed := GuessDecode(encoded)
+	if !bytes.Equal(decoded, input) || !slices.Equal(guessed, []string{"url", "b64", "gunzip"}) {
+		t.Errorf("GuessDecode = %q via %v", decoded, guessed)
+	}
+	if _, guessed := GuessDecode([]byte("plain text")); len(guessed) != 0 {
+		t.Errorf("GuessDecode(plain text) detected %v", guessed)
+	}
+}
@@ -3,6 +3,7 @@ package anbuGenerics
 import (
 	cryptoRand "crypto/rand"
 	"math/big"
+	"slices"
 	"strings"
 )
 
@@ -122,6 +123,12 @@ func GeneratePassPhrase(wordsCount int, separator string, capitalize bool) (stri
 	return result.String(), nil
 }
 
+// PassphraseWords returns a copy of the passphrase word list, which also serves
+// as the dictionary for password audits
+func PassphraseWords() []string {
+	return slices.Clone(passphraseWords)
+}
+
 var passphraseWords = []string{
 	"abandoned",
 	"abandons",
@@ -223,7 +223,7 @@ func StashList() error {
 	table := u.NewTable([]string{"ID", "Type", "Name", "Added"})
 	for _, entry := range index.Entries {
 		timeAgo := time.Since(entry.CreatedAt)
-		timeAgoStr := formatTimeAgo(timeAgo)
+		timeAgoStr := u.FormatTimeAgo(timeAgo)
 		table.Rows = append(table.Rows, []string{
 			fmt.Sprintf("%d", entry.ID),
 			string(entry.Type),
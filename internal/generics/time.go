@@ -15,10 +15,11 @@ type timeFormat struct {
 	Value  string
 }
 
-func printTimeTable(concern time.Time) {
+// TimeFormatRows returns the format/value rows of `anbu time` for reuse by
+// other commands that display timestamps
+func TimeFormatRows(concern time.Time) [][]string {
 	utcTime := concern.UTC()
 	localTime := concern.Local()
-	table := u.NewTable([]string{"Format", "Value"})
 	timeFormats := []timeFormat{
 		{"ISO8601 UTC", utcTime.Format(time.RFC3339)},
 		{"Human UTC", utcTime.Format("Mon Jan 2 15:04:05 MST 2006")},
@@ -31,12 +32,28 @@ func printTimeTable(concern time.Time) {
 		{"Time Only", localTime.Format("15:04:05")},
 		{"Database", localTime.Format("2006-01-02 15:04:05")},
 	}
+	var rows [][]string
 	for _, format := range timeFormats {
-		table.Rows = append(table.Rows, []string{format.Format, format.Value})
+		rows = append(rows, []string{format.Format, format.Value})
 	}
+	return rows
+}
+
+func printTimeTable(concern time.Time) {
+	table := u.NewTable([]string{"Format", "Value"})
+	table.Rows = TimeFormatRows(concern)
 	table.PrintTable(false)
 }
 
+// TimeRelative describes target relative to now, e.g. "in 2 hours" or "3 days ago"
+func TimeRelative(target time.Time) string {
+	now := time.Now()
+	if target.After(now) {
+		return "in " + timeFormatDuration(target.Sub(now))
+	}
+	return timeFormatDuration(now.Sub(target)) + " ago"
+}
+
 func printTimeTablePurple(concern time.Time) {
 	utcTime := concern.UTC()
 	table := u.NewTable([]string{"Item", "Value"})
@@ -17,6 +17,7 @@ type HTTPServerOptions struct {
 	ListenAddress string
 	EnableUpload  bool
 	EnableTLS     bool
+	Certificate   *tls.Certificate
 }
 
 type HTTPServer struct {
@@ -276,6 +277,11 @@ func streamPartToUniqueFile(filename string, part io.Reader) (string, int64, err
 }
 
 func (s *HTTPServer) getTLSConfig() (*tls.Config, error) {
+	if s.Options.Certificate != nil {
+		return &tls.Config{
+			Certificates: []tls.Certificate{*s.Options.Certificate},
+		}, nil
+	}
 	cert, err := u.GenerateSelfSignedCert()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate self-signed certificate: %w", err)
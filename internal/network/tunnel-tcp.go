@@ -12,7 +12,7 @@ import (
 	u "github.com/tanq16/anbu/utils"
 )
 
-func TCPTunnel(ctx context.Context, localAddr, remoteAddr string, useTLS, insecureSkipVerify bool) {
+func TCPTunnel(ctx context.Context, localAddr, remoteAddr string, tlsConfig *tls.Config) {
 	u.PrintInfo(fmt.Sprintf("TCP tunnel %s %s %s", localAddr, u.StyleSymbols["arrow"], remoteAddr))
 
 	listener, err := net.Listen("tcp", localAddr)
@@ -21,7 +21,7 @@ func TCPTunnel(ctx context.Context, localAddr, remoteAddr string, useTLS, insecu
 	}
 	defer listener.Close()
 	u.PrintInfo(fmt.Sprintf("Listening on %s", localAddr))
-	if useTLS {
+	if tlsConfig != nil {
 		u.PrintStream("Using TLS for remote connections")
 	}
 
@@ -65,10 +65,7 @@ func TCPTunnel(ctx context.Context, localAddr, remoteAddr string, useTLS, insecu
 				u.PrintInfo(fmt.Sprintf("New connection from %s", localConn.RemoteAddr()))
 
 				var remoteConn net.Conn
-				if useTLS {
-					tlsConfig := &tls.Config{
-						InsecureSkipVerify: insecureSkipVerify,
-					}
+				if tlsConfig != nil {
 					remoteConn, err = tls.Dial("tcp", remoteAddr, tlsConfig)
 				} else {
 					remoteConn, err = net.Dial("tcp", remoteAddr)
@@ -3,14 +3,25 @@ package utils
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"hash"
+	"io"
 	"os"
 )
 
 func ComputeFileHash(filePath string) (string, error) {
-	data, err := os.ReadFile(filePath)
+	return ComputeFileHashWith(filePath, sha256.New())
+}
+
+// ComputeFileHashWith streams the file through h, so memory use does not
+// grow with the file size
+func ComputeFileHashWith(filePath string, h hash.Hash) (string, error) {
+	file, err := os.Open(filePath)
 	if err != nil {
 		return "", err
 	}
-	hash := sha256.Sum256(data)
-	return hex.EncodeToString(hash[:]), nil
+	defer file.Close()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
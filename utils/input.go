@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bufio"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -34,6 +35,16 @@ func ReadPipedInput() string {
 	return strings.TrimSpace(strings.Join(lines, "\n"))
 }
 
+// ReadPipedBytes returns piped stdin exactly as given (no trimming, line
+// ending changes or line length limit), or nil when stdin is a terminal
+func ReadPipedBytes() ([]byte, error) {
+	fi, err := os.Stdin.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice != 0 {
+		return nil, nil
+	}
+	return io.ReadAll(os.Stdin)
+}
+
 func ReadPipedLine() string {
 	fi, err := os.Stdin.Stat()
 	if err != nil || fi.Mode()&os.ModeCharDevice != 0 {
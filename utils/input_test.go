@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadPipedBytes(t *testing.T) {
+	long := bytes.Repeat([]byte{0x00, 0xff, ' ', '\t'}, 100*1024)
+	input := append([]byte("  a\r\nb\n"), long...)
+	path := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(path, input, 0600); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open input: %v", err)
+	}
+	defer file.Close()
+	stdin := os.Stdin
+	os.Stdin = file
+	defer func() { os.Stdin = stdin }()
+
+	got, err := ReadPipedBytes()
+	if err != nil {
+		t.Fatalf("ReadPipedBytes error = %v", err)
+	}
+	if !bytes.Equal(got, input) {
+		t.Errorf("ReadPipedBytes returned %d bytes, want the %d input bytes unchanged", len(got), len(input))
+	}
+}
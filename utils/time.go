@@ -1,11 +1,11 @@
-package anbuGenerics
+package utils
 
 import (
 	"fmt"
 	"time"
 )
 
-func formatTimeAgo(d time.Duration) string {
+func FormatTimeAgo(d time.Duration) string {
 	if d < time.Minute {
 		return fmt.Sprintf("%ds ago", int(d.Seconds()))
 	}